@@ -4,10 +4,7 @@ This is an example application to demonstrate parsing an ID Token.
 package main
 
 import (
-	"crypto/rand"
-	"encoding/base64"
 	"encoding/json"
-	"io"
 	"log"
 	"net/http"
 	"os"
@@ -23,14 +20,6 @@ var (
 	clientSecret = os.Getenv("GOOGLE_OAUTH2_CLIENT_SECRET")
 )
 
-func randString(nByte int) (string, error) {
-	b := make([]byte, nByte)
-	if _, err := io.ReadFull(rand.Reader, b); err != nil {
-		return "", err
-	}
-	return base64.RawURLEncoding.EncodeToString(b), nil
-}
-
 func setCallbackCookie(w http.ResponseWriter, r *http.Request, name, value string) {
 	c := &http.Cookie{
 		Name:     name,
@@ -49,72 +38,47 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	oidcConfig := &oidc.Config{
-		ClientID: clientID,
-	}
-	verifier := provider.Verifier(oidcConfig)
-
-	config := oauth2.Config{
+	flow := provider.AuthCodeFlow(&oauth2.Config{
 		ClientID:     clientID,
 		ClientSecret: clientSecret,
-		Endpoint:     provider.Endpoint(),
 		RedirectURL:  "http://127.0.0.1:5556/auth/google/callback",
 		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
-	}
+	}, &oidc.Config{})
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-		state, err := randString(16)
+		authURL, state, err := flow.AuthCodeURL()
 		if err != nil {
 			http.Error(w, "Internal error", http.StatusInternalServerError)
 			return
 		}
-		nonce, err := randString(16)
-		if err != nil {
-			http.Error(w, "Internal error", http.StatusInternalServerError)
-			return
-		}
-		setCallbackCookie(w, r, "state", state)
-		setCallbackCookie(w, r, "nonce", nonce)
+		setCallbackCookie(w, r, "state", state.State)
+		setCallbackCookie(w, r, "nonce", state.Nonce)
+		setCallbackCookie(w, r, "code_verifier", state.CodeVerifier)
 
-		http.Redirect(w, r, config.AuthCodeURL(state, oidc.Nonce(nonce)), http.StatusFound)
+		http.Redirect(w, r, authURL, http.StatusFound)
 	})
 
 	http.HandleFunc("/auth/google/callback", func(w http.ResponseWriter, r *http.Request) {
-		state, err := r.Cookie("state")
-		if err != nil {
-			http.Error(w, "state not found", http.StatusBadRequest)
-			return
-		}
-		if r.URL.Query().Get("state") != state.Value {
-			http.Error(w, "state did not match", http.StatusBadRequest)
-			return
-		}
-
-		oauth2Token, err := config.Exchange(ctx, r.URL.Query().Get("code"))
-		if err != nil {
-			http.Error(w, "Failed to exchange token: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-		rawIDToken, ok := oauth2Token.Extra("id_token").(string)
-		if !ok {
-			http.Error(w, "No id_token field in oauth2 token.", http.StatusInternalServerError)
-			return
-		}
-		idToken, err := verifier.Verify(ctx, rawIDToken)
-		if err != nil {
-			http.Error(w, "Failed to verify ID Token: "+err.Error(), http.StatusInternalServerError)
-			return
+		var state oidc.AuthCodeState
+		for name, value := range map[string]*string{
+			"state":         &state.State,
+			"nonce":         &state.Nonce,
+			"code_verifier": &state.CodeVerifier,
+		} {
+			c, err := r.Cookie(name)
+			if err != nil {
+				http.Error(w, name+" not found", http.StatusBadRequest)
+				return
+			}
+			*value = c.Value
 		}
 
-		nonce, err := r.Cookie("nonce")
+		result, err := flow.Exchange(ctx, &state, r.URL.Query())
 		if err != nil {
-			http.Error(w, "nonce not found", http.StatusBadRequest)
-			return
-		}
-		if idToken.Nonce != nonce.Value {
-			http.Error(w, "nonce did not match", http.StatusBadRequest)
+			http.Error(w, "Failed to complete login: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
+		oauth2Token, idToken := result.Token, result.IDToken
 
 		oauth2Token.AccessToken = "*REDACTED*"
 
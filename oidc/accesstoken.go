@@ -0,0 +1,248 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// accessTokenType is the typ header value of JWT access tokens.
+const accessTokenType = "at+jwt"
+
+// AccessTokenVerifier provides verification for JWT access tokens, as defined by
+// RFC 9068. It's intended for resource servers which receive access tokens issued
+// by a provider, and rejects other JWTs from the same issuer, such as ID Tokens.
+//
+// See: https://www.rfc-editor.org/rfc/rfc9068
+type AccessTokenVerifier struct {
+	keySet KeySet
+	config *AccessTokenConfig
+	issuer string
+
+	// Refreshing provider the verifier was created from, if any. Supplies the
+	// signing algorithms when the config doesn't specify them.
+	provider *Provider
+}
+
+// AccessTokenConfig is the configuration for an AccessTokenVerifier.
+type AccessTokenConfig struct {
+	// Resource identifier of this resource server. The audience of the token must
+	// contain this value.
+	//
+	// If not provided, users must explicitly set SkipAudienceCheck.
+	Audience string
+	// If true, no audience check is performed. Must be true if Audience is empty.
+	SkipAudienceCheck bool
+
+	// If specified, the client_id claim must be one of these values.
+	AllowedClientIDs []string
+	// If specified, the scope claim must contain all of these values.
+	RequiredScopes []string
+
+	// If specified, only this set of algorithms may be used to sign the JWT.
+	//
+	// If the AccessTokenVerifier is created from a provider with
+	// (*Provider).AccessTokenVerifier, this defaults to the set of algorithms the
+	// provider supports for ID Tokens. Otherwise this values defaults to RS256.
+	SupportedSigningAlgs []string
+
+	// If true, token expiry is not checked.
+	SkipExpiryCheck bool
+	// ClockSkew is the leeway allowed when comparing the exp and nbf claims against
+	// the current time.
+	ClockSkew time.Duration
+	// Time function to check Token expiry. Defaults to time.Now
+	Now func() time.Time
+}
+
+// NewAccessTokenVerifier returns a verifier manually constructed from a key set and
+// issuer URL.
+//
+//	keySet := oidc.NewRemoteKeySet(ctx, "https://login.example.com/keys")
+//	verifier := oidc.NewAccessTokenVerifier("https://login.example.com", keySet, &oidc.AccessTokenConfig{
+//		Audience: "https://api.example.com",
+//	})
+func NewAccessTokenVerifier(issuerURL string, keySet KeySet, config *AccessTokenConfig) *AccessTokenVerifier {
+	return &AccessTokenVerifier{keySet: keySet, config: config, issuer: issuerURL}
+}
+
+// AccessTokenVerifier returns an AccessTokenVerifier that uses the provider's key set
+// to verify JWT access tokens.
+//
+// The returned verifier uses a background context for all requests to the upstream
+// JWKs endpoint.
+func (p *Provider) AccessTokenVerifier(config *AccessTokenConfig) *AccessTokenVerifier {
+	keySet := p.remoteKeySet()
+
+	p.discoveryMu.RLock()
+	defer p.discoveryMu.RUnlock()
+
+	v := NewAccessTokenVerifier(p.issuer, keySet, config)
+	if p.stopRefreshing != nil {
+		// Algorithms may change, look them up during verification.
+		v.provider = p
+		return v
+	}
+	if len(config.SupportedSigningAlgs) == 0 && len(p.algorithms) > 0 {
+		// Make a copy so we don't modify the config values.
+		cp := &AccessTokenConfig{}
+		*cp = *config
+		cp.SupportedSigningAlgs = p.algorithms
+		v.config = cp
+	}
+	return v
+}
+
+// AccessToken is a JWT access token verified by an AccessTokenVerifier.
+type AccessToken struct {
+	// The URL of the server which issued this token.
+	Issuer string
+	// Resource identifiers this token is intended for.
+	Audience []string
+	// The end user the token was issued for, or the client for tokens issued
+	// without an end user.
+	Subject string
+	// The client the token was issued to.
+	ClientID string
+	// Scopes granted to the token.
+	Scopes []string
+	// Unique identifier of the token.
+	JWTID string
+
+	// Expiry of the token.
+	Expiry time.Time
+	// When the token was issued by the provider.
+	IssuedAt time.Time
+
+	// Header of the token.
+	Header JOSEHeader
+
+	// Raw payload of the token.
+	claims []byte
+}
+
+// Claims unmarshals the raw JSON payload of the access token into a provided struct.
+func (a *AccessToken) Claims(v interface{}) error {
+	if a.claims == nil {
+		return errors.New("oidc: claims not set")
+	}
+	return json.Unmarshal(a.claims, v)
+}
+
+type accessToken struct {
+	Issuer    string    `json:"iss"`
+	Subject   string    `json:"sub"`
+	Audience  audience  `json:"aud"`
+	ClientID  string    `json:"client_id"`
+	Scope     string    `json:"scope"`
+	JWTID     string    `json:"jti"`
+	Expiry    *jsonTime `json:"exp"`
+	IssuedAt  *jsonTime `json:"iat"`
+	NotBefore *jsonTime `json:"nbf"`
+}
+
+// Verify parses a raw JWT access token, verifies it's been signed by the provider,
+// performs the checks required by RFC 9068 and any additional checks depending on
+// the config, and returns the token.
+//
+//	token, err := verifier.Verify(ctx, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+//	if err != nil {
+//		// handle error
+//	}
+func (v *AccessTokenVerifier) Verify(ctx context.Context, rawAccessToken string) (*AccessToken, error) {
+	header, err := parseJWTHeader(rawAccessToken)
+	if err != nil {
+		return nil, err
+	}
+	// Rejects ID Tokens and other JWTs from the same issuer.
+	if !isMediaType(header.Type, accessTokenType) {
+		return nil, fmt.Errorf("oidc: expected jwt with typ %q got %q", accessTokenType, header.Type)
+	}
+
+	payload, err := parseJWT(rawAccessToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: malformed jwt: %v", err)
+	}
+	var token accessToken
+	if err := json.Unmarshal(payload, &token); err != nil {
+		return nil, fmt.Errorf("oidc: failed to unmarshal claims: %v", err)
+	}
+
+	switch {
+	case token.Expiry == nil:
+		return nil, errors.New("oidc: access token has no exp claim")
+	case token.IssuedAt == nil:
+		return nil, errors.New("oidc: access token has no iat claim")
+	case token.Subject == "":
+		return nil, errors.New("oidc: access token has no sub claim")
+	case token.ClientID == "":
+		return nil, errors.New("oidc: access token has no client_id claim")
+	case token.JWTID == "":
+		return nil, errors.New("oidc: access token has no jti claim")
+	}
+
+	t := &AccessToken{
+		Issuer:   token.Issuer,
+		Audience: []string(token.Audience),
+		Subject:  token.Subject,
+		ClientID: token.ClientID,
+		Scopes:   strings.Fields(token.Scope),
+		JWTID:    token.JWTID,
+		Expiry:   time.Time(*token.Expiry),
+		IssuedAt: time.Time(*token.IssuedAt),
+		Header:   *header,
+		claims:   payload,
+	}
+
+	if t.Issuer != v.issuer {
+		return nil, fmt.Errorf("oidc: access token issued by a different provider, expected %q got %q", v.issuer, t.Issuer)
+	}
+
+	if !v.config.SkipAudienceCheck {
+		if v.config.Audience == "" {
+			return nil, errors.New("oidc: invalid configuration, audience must be provided or SkipAudienceCheck must be set")
+		}
+		if !contains(t.Audience, v.config.Audience) {
+			return nil, fmt.Errorf("oidc: expected audience %q got %q", v.config.Audience, t.Audience)
+		}
+	}
+
+	if len(v.config.AllowedClientIDs) > 0 && !contains(v.config.AllowedClientIDs, t.ClientID) {
+		return nil, fmt.Errorf("oidc: access token issued to client %q which is not allowed", t.ClientID)
+	}
+	for _, scope := range v.config.RequiredScopes {
+		if !contains(t.Scopes, scope) {
+			return nil, fmt.Errorf("oidc: access token missing required scope %q", scope)
+		}
+	}
+
+	if !v.config.SkipExpiryCheck {
+		now := time.Now
+		if v.config.Now != nil {
+			now = v.config.Now
+		}
+		nowTime := now()
+
+		if t.Expiry.Before(nowTime.Add(-v.config.ClockSkew)) {
+			return nil, &TokenExpiredError{Expiry: t.Expiry}
+		}
+		if token.NotBefore != nil {
+			nbfTime := time.Time(*token.NotBefore)
+			if nowTime.Add(v.config.ClockSkew).Before(nbfTime) {
+				return nil, fmt.Errorf("oidc: current time %v before the nbf (not before) time: %v", nowTime, nbfTime)
+			}
+		}
+	}
+
+	algs := v.config.SupportedSigningAlgs
+	if len(algs) == 0 && v.provider != nil {
+		algs = v.provider.signingAlgorithms()
+	}
+	if _, err := verifySignature(ctx, v.keySet, rawAccessToken, payload, algs, "access token"); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
@@ -0,0 +1,186 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAccessTokenVerifier(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	exp := strconv.FormatInt(now.Add(time.Hour).Unix(), 10)
+	iat := strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)
+	claims := func(extra string) string {
+		return `{"iss":"https://foo","aud":"https://api.example.com","sub":"user1","client_id":"client1",` +
+			`"jti":"abc","scope":"read write","exp":` + exp + `,"iat":` + iat + extra + `}`
+	}
+
+	tests := []struct {
+		name    string
+		typ     string
+		payload string
+		config  AccessTokenConfig
+		wantErr bool
+	}{
+		{
+			name:    "good token",
+			typ:     "at+jwt",
+			payload: claims(""),
+			config:  AccessTokenConfig{Audience: "https://api.example.com"},
+		},
+		{
+			name:    "media type with application prefix",
+			typ:     "application/AT+JWT",
+			payload: claims(""),
+			config:  AccessTokenConfig{Audience: "https://api.example.com"},
+		},
+		{
+			name:    "id token",
+			typ:     "JWT",
+			payload: claims(""),
+			config:  AccessTokenConfig{Audience: "https://api.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "no typ",
+			payload: claims(""),
+			config:  AccessTokenConfig{Audience: "https://api.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			typ:     "at+jwt",
+			payload: claims(""),
+			config:  AccessTokenConfig{Audience: "https://other.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "no audience configured",
+			typ:     "at+jwt",
+			payload: claims(""),
+			wantErr: true,
+		},
+		{
+			name:    "skip audience check",
+			typ:     "at+jwt",
+			payload: claims(""),
+			config:  AccessTokenConfig{SkipAudienceCheck: true},
+		},
+		{
+			name:    "allowed client",
+			typ:     "at+jwt",
+			payload: claims(""),
+			config:  AccessTokenConfig{Audience: "https://api.example.com", AllowedClientIDs: []string{"client1"}},
+		},
+		{
+			name:    "client not allowed",
+			typ:     "at+jwt",
+			payload: claims(""),
+			config:  AccessTokenConfig{Audience: "https://api.example.com", AllowedClientIDs: []string{"client2"}},
+			wantErr: true,
+		},
+		{
+			name:    "required scopes",
+			typ:     "at+jwt",
+			payload: claims(""),
+			config:  AccessTokenConfig{Audience: "https://api.example.com", RequiredScopes: []string{"write", "read"}},
+		},
+		{
+			name:    "missing scope",
+			typ:     "at+jwt",
+			payload: claims(""),
+			config:  AccessTokenConfig{Audience: "https://api.example.com", RequiredScopes: []string{"admin"}},
+			wantErr: true,
+		},
+		{
+			name:    "missing client_id",
+			typ:     "at+jwt",
+			payload: `{"iss":"https://foo","aud":"https://api.example.com","sub":"user1","jti":"abc","exp":` + exp + `,"iat":` + iat + `}`,
+			config:  AccessTokenConfig{Audience: "https://api.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "missing jti",
+			typ:     "at+jwt",
+			payload: `{"iss":"https://foo","aud":"https://api.example.com","sub":"user1","client_id":"client1","exp":` + exp + `,"iat":` + iat + `}`,
+			config:  AccessTokenConfig{Audience: "https://api.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			typ:     "at+jwt",
+			payload: `{"iss":"https://bar","aud":"https://api.example.com","sub":"user1","client_id":"client1","jti":"abc","exp":` + exp + `,"iat":` + iat + `}`,
+			config:  AccessTokenConfig{Audience: "https://api.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			typ:     "at+jwt",
+			payload: claims(""),
+			config: AccessTokenConfig{
+				Audience: "https://api.example.com",
+				Now:      func() time.Time { return now.Add(2 * time.Hour) },
+			},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key := newRSAKey(t)
+			config := test.config
+			if config.Now == nil {
+				config.Now = func() time.Time { return now }
+			}
+			v := NewAccessTokenVerifier("https://foo", &StaticKeySet{PublicKeys: []crypto.PublicKey{key.pub}}, &config)
+			token, err := v.Verify(context.Background(), key.signWithType(t, []byte(test.payload), test.typ))
+			if err != nil {
+				if !test.wantErr {
+					t.Fatalf("Verify() failed: %v", err)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatalf("Verify(): expected error")
+			}
+			if token.ClientID != "client1" || token.Subject != "user1" || token.JWTID != "abc" {
+				t.Errorf("unexpected token %+v", token)
+			}
+			if !reflect.DeepEqual(token.Scopes, []string{"read", "write"}) {
+				t.Errorf("unexpected scopes %q", token.Scopes)
+			}
+			var c map[string]interface{}
+			if err := token.Claims(&c); err != nil {
+				t.Fatalf("Claims() failed: %v", err)
+			}
+			if c["client_id"] != "client1" {
+				t.Errorf("unexpected claims %v", c)
+			}
+		})
+	}
+}
+
+func TestAccessTokenVerifierBadSignature(t *testing.T) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"iss":       "https://foo",
+		"aud":       "https://api.example.com",
+		"sub":       "user1",
+		"client_id": "client1",
+		"jti":       "abc",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"iat":       time.Now().Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	signKey, verificationKey := newRSAKey(t), newRSAKey(t)
+	v := NewAccessTokenVerifier("https://foo", &StaticKeySet{PublicKeys: []crypto.PublicKey{verificationKey.pub}}, &AccessTokenConfig{
+		Audience: "https://api.example.com",
+	})
+	if _, err := v.Verify(context.Background(), signKey.signWithType(t, payload, "at+jwt")); err == nil {
+		t.Errorf("expected error for token signed by a different key")
+	}
+}
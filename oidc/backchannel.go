@@ -0,0 +1,323 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// logoutTokenType is the typ header value of logout tokens.
+	logoutTokenType = "logout+jwt"
+	// backChannelLogoutEvent is the member of the events claim which identifies a
+	// JWT as a logout token.
+	backChannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+)
+
+// ReplayCache records the IDs of tokens which have already been used, so they can
+// be rejected if they're presented again.
+//
+// Implementations must be safe for concurrent use. Applications running multiple
+// instances should use an implementation backed by shared storage.
+type ReplayCache interface {
+	// Add records an ID until its expiry. It returns false if the ID was already
+	// recorded and hasn't expired.
+	Add(ctx context.Context, id string, expiry time.Time) (bool, error)
+	// Remove forgets a recorded ID, so it's accepted again. Removing an ID which
+	// isn't recorded isn't an error.
+	Remove(ctx context.Context, id string) error
+}
+
+// MemoryReplayCache is a ReplayCache which holds IDs in memory. The zero value is
+// ready to use.
+type MemoryReplayCache struct {
+	// Time function used to expire IDs. Defaults to time.Now
+	Now func() time.Time
+
+	mu  sync.Mutex
+	ids map[string]time.Time
+}
+
+// Add records an ID until its expiry, removing any expired IDs.
+func (c *MemoryReplayCache) Add(ctx context.Context, id string, expiry time.Time) (bool, error) {
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	nowTime := now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ids == nil {
+		c.ids = make(map[string]time.Time)
+	}
+	for k, exp := range c.ids {
+		if exp.Before(nowTime) {
+			delete(c.ids, k)
+		}
+	}
+	if _, ok := c.ids[id]; ok {
+		return false, nil
+	}
+	c.ids[id] = expiry
+	return true, nil
+}
+
+// Remove forgets a recorded ID.
+func (c *MemoryReplayCache) Remove(ctx context.Context, id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.ids, id)
+	return nil
+}
+
+// LogoutTokenVerifier provides verification for logout tokens sent by a provider
+// through OpenID Connect Back-Channel Logout.
+//
+// See: https://openid.net/specs/openid-connect-backchannel-1_0.html
+type LogoutTokenVerifier struct {
+	verifier    *IDTokenVerifier
+	replayCache ReplayCache
+}
+
+// NewLogoutTokenVerifier returns a verifier which uses the key set, issuer, and
+// config of an IDTokenVerifier to verify logout tokens. The config's ClientID,
+// SkipClientIDCheck, SkipIssuerCheck, SupportedSigningAlgs, ClockSkew, and Now
+// fields are used, other checks only apply to ID Tokens.
+//
+// The IDs of verified tokens are recorded in the replayCache, and tokens whose ID
+// has already been recorded are rejected. If replayCache is nil, a
+// MemoryReplayCache is used.
+func NewLogoutTokenVerifier(verifier *IDTokenVerifier, replayCache ReplayCache) *LogoutTokenVerifier {
+	if replayCache == nil {
+		replayCache = &MemoryReplayCache{Now: verifier.config.Now}
+	}
+	return &LogoutTokenVerifier{verifier: verifier, replayCache: replayCache}
+}
+
+// LogoutTokenVerifier returns a LogoutTokenVerifier that uses the provider's key
+// set to verify logout tokens. See NewLogoutTokenVerifier for the config fields
+// which apply and the use of the replayCache.
+func (p *Provider) LogoutTokenVerifier(config *Config, replayCache ReplayCache) *LogoutTokenVerifier {
+	return NewLogoutTokenVerifier(p.Verifier(config), replayCache)
+}
+
+// LogoutToken is a logout token verified by a LogoutTokenVerifier. At least one of
+// Subject or SessionID is set.
+type LogoutToken struct {
+	// The URL of the server which issued this token.
+	Issuer string
+	// The client ID, or set of client IDs, that this token is for.
+	Audience []string
+	// The end user whose sessions should be terminated, if set.
+	Subject string
+	// The session at the provider which was terminated, if set. This matches the
+	// sid claim of ID Tokens issued for the session.
+	SessionID string
+	// Unique identifier of the token.
+	JWTID string
+
+	// Expiry of the token.
+	Expiry time.Time
+	// When the token was issued by the provider.
+	IssuedAt time.Time
+
+	// Header of the token.
+	Header JOSEHeader
+
+	// Raw payload of the token.
+	claims []byte
+}
+
+// Claims unmarshals the raw JSON payload of the logout token into a provided struct.
+func (l *LogoutToken) Claims(v interface{}) error {
+	if l.claims == nil {
+		return errors.New("oidc: claims not set")
+	}
+	return json.Unmarshal(l.claims, v)
+}
+
+type logoutToken struct {
+	Issuer    string                     `json:"iss"`
+	Subject   string                     `json:"sub"`
+	Audience  audience                   `json:"aud"`
+	SessionID string                     `json:"sid"`
+	JWTID     string                     `json:"jti"`
+	Expiry    *jsonTime                  `json:"exp"`
+	IssuedAt  *jsonTime                  `json:"iat"`
+	Events    map[string]json.RawMessage `json:"events"`
+	Nonce     *string                    `json:"nonce"`
+}
+
+// Verify parses a raw logout token, verifies it's been signed by the provider,
+// performs the validation required by OpenID Connect Back-Channel Logout, and
+// returns the token.
+//
+// Each token is only accepted once. Tokens whose jti has been seen before are
+// rejected.
+//
+// See: https://openid.net/specs/openid-connect-backchannel-1_0.html#Validation
+func (v *LogoutTokenVerifier) Verify(ctx context.Context, rawLogoutToken string) (*LogoutToken, error) {
+	config := v.verifier.config
+
+	header, err := parseJWTHeader(rawLogoutToken)
+	if err != nil {
+		return nil, err
+	}
+	if !isMediaType(header.Type, logoutTokenType) {
+		return nil, fmt.Errorf("oidc: expected jwt with typ %q got %q", logoutTokenType, header.Type)
+	}
+
+	payload, err := parseJWT(rawLogoutToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: malformed jwt: %v", err)
+	}
+	var token logoutToken
+	if err := json.Unmarshal(payload, &token); err != nil {
+		return nil, fmt.Errorf("oidc: failed to unmarshal claims: %v", err)
+	}
+
+	switch {
+	case token.Expiry == nil:
+		return nil, errors.New("oidc: logout token has no exp claim")
+	case token.IssuedAt == nil:
+		return nil, errors.New("oidc: logout token has no iat claim")
+	case token.JWTID == "":
+		return nil, errors.New("oidc: logout token has no jti claim")
+	case token.Subject == "" && token.SessionID == "":
+		return nil, errors.New("oidc: logout token has neither a sub nor a sid claim")
+	case token.Nonce != nil:
+		// Prevents ID Tokens from being used as logout tokens.
+		return nil, errors.New("oidc: logout token must not contain a nonce claim")
+	}
+	event, ok := token.Events[backChannelLogoutEvent]
+	if !ok {
+		return nil, fmt.Errorf("oidc: logout token events claim has no %q member", backChannelLogoutEvent)
+	}
+	var eventValue map[string]interface{}
+	if err := json.Unmarshal(event, &eventValue); err != nil || eventValue == nil {
+		return nil, fmt.Errorf("oidc: logout token %q event must be a JSON object", backChannelLogoutEvent)
+	}
+
+	t := &LogoutToken{
+		Issuer:    token.Issuer,
+		Audience:  []string(token.Audience),
+		Subject:   token.Subject,
+		SessionID: token.SessionID,
+		JWTID:     token.JWTID,
+		Expiry:    time.Time(*token.Expiry),
+		IssuedAt:  time.Time(*token.IssuedAt),
+		Header:    *header,
+		claims:    payload,
+	}
+
+	if !config.SkipIssuerCheck && t.Issuer != v.verifier.issuer {
+		return nil, fmt.Errorf("oidc: logout token issued by a different provider, expected %q got %q", v.verifier.issuer, t.Issuer)
+	}
+	if !config.SkipClientIDCheck {
+		if config.ClientID == "" {
+			return nil, errors.New("oidc: invalid configuration, clientID must be provided or SkipClientIDCheck must be set")
+		}
+		if !contains(t.Audience, config.ClientID) {
+			return nil, fmt.Errorf("oidc: expected audience %q got %q", config.ClientID, t.Audience)
+		}
+	}
+
+	now := time.Now
+	if config.Now != nil {
+		now = config.Now
+	}
+	nowTime := now()
+	if t.Expiry.Before(nowTime.Add(-config.ClockSkew)) {
+		return nil, &TokenExpiredError{Expiry: t.Expiry}
+	}
+	if nowTime.Add(config.ClockSkew).Before(t.IssuedAt) {
+		return nil, fmt.Errorf("oidc: logout token issued in the future, current time %v before the iat (issued at) time: %v", nowTime, t.IssuedAt)
+	}
+
+	algs := config.SupportedSigningAlgs
+	if len(algs) == 0 && v.verifier.provider != nil {
+		algs = v.verifier.provider.signingAlgorithms()
+	}
+	if _, err := verifySignature(ctx, v.verifier.keySet, rawLogoutToken, payload, algs, "logout token"); err != nil {
+		return nil, err
+	}
+
+	// Only record tokens once they're known to come from the provider, so forged
+	// tokens can't block legitimate ones.
+	added, err := v.replayCache.Add(ctx, t.replayID(), t.Expiry.Add(config.ClockSkew))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: failed to record logout token: %v", err)
+	}
+	if !added {
+		return nil, fmt.Errorf("oidc: logout token %q has already been used", t.JWTID)
+	}
+	return t, nil
+}
+
+// replayID is the ID under which the token is recorded in a ReplayCache. The jti
+// is only unique per issuer.
+func (l *LogoutToken) replayID() string {
+	return l.Issuer + " " + l.JWTID
+}
+
+// BackChannelLogoutHandler returns an http.Handler for the back-channel logout
+// endpoint registered with the provider. It verifies the logout token of each
+// request and calls logout with the verified token, which should terminate the
+// sessions identified by the token's Subject or SessionID.
+//
+//	verifier := provider.LogoutTokenVerifier(&oidc.Config{ClientID: clientID}, nil)
+//	http.Handle("/backchannel-logout", oidc.BackChannelLogoutHandler(verifier,
+//		func(ctx context.Context, token *oidc.LogoutToken) error {
+//			return sessions.DeleteBySessionID(ctx, token.SessionID)
+//		}))
+//
+// Invalid requests and failed logouts are answered with 400 Bad Request, as
+// required by the specification. If logout fails, the token's ID is removed from
+// the verifier's ReplayCache so the provider can retry the request with the same
+// token.
+//
+// See: https://openid.net/specs/openid-connect-backchannel-1_0.html#BCRequest
+func BackChannelLogoutHandler(verifier *LogoutTokenVerifier, logout func(ctx context.Context, token *LogoutToken) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		rawLogoutToken := r.PostFormValue("logout_token")
+		if rawLogoutToken == "" {
+			writeLogoutError(w, "missing logout_token")
+			return
+		}
+		token, err := verifier.Verify(r.Context(), rawLogoutToken)
+		if err != nil {
+			writeLogoutError(w, err.Error())
+			return
+		}
+		if err := logout(r.Context(), token); err != nil {
+			// Best effort. If the ID can't be removed, retries are rejected as
+			// replays, which is no worse than not retrying.
+			verifier.replayCache.Remove(r.Context(), token.replayID())
+			writeLogoutError(w, "logout failed")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// writeLogoutError writes an OAuth 2.0 error response for a back-channel logout
+// request.
+func writeLogoutError(w http.ResponseWriter, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(struct {
+		Code        string `json:"error"`
+		Description string `json:"error_description"`
+	}{ErrorInvalidRequest, description})
+}
@@ -0,0 +1,397 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogoutTokenVerifier(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	exp := strconv.FormatInt(now.Add(2*time.Minute).Unix(), 10)
+	iat := strconv.FormatInt(now.Add(-time.Minute).Unix(), 10)
+	claims := func(extra string) string {
+		return `{"iss":"https://foo","aud":"client1","jti":"abc","exp":` + exp + `,"iat":` + iat + extra + `}`
+	}
+	const events = `,"events":{"http://schemas.openid.net/event/backchannel-logout":{}}`
+
+	tests := []struct {
+		name    string
+		typ     string
+		payload string
+		config  Config
+		want    LogoutToken
+		wantErr bool
+	}{
+		{
+			name:    "good token",
+			typ:     "logout+jwt",
+			payload: claims(`,"sub":"user1","sid":"session1"` + events),
+			config:  Config{ClientID: "client1"},
+			want:    LogoutToken{Subject: "user1", SessionID: "session1"},
+		},
+		{
+			name:    "sid only",
+			typ:     "application/logout+jwt",
+			payload: claims(`,"sid":"session1"` + events),
+			config:  Config{ClientID: "client1"},
+			want:    LogoutToken{SessionID: "session1"},
+		},
+		{
+			name:    "sub only",
+			typ:     "logout+jwt",
+			payload: claims(`,"sub":"user1"` + events),
+			config:  Config{ClientID: "client1"},
+			want:    LogoutToken{Subject: "user1"},
+		},
+		{
+			name:    "id token typ",
+			typ:     "JWT",
+			payload: claims(`,"sub":"user1"` + events),
+			config:  Config{ClientID: "client1"},
+			wantErr: true,
+		},
+		{
+			name:    "no typ",
+			payload: claims(`,"sub":"user1"` + events),
+			config:  Config{ClientID: "client1"},
+			wantErr: true,
+		},
+		{
+			name:    "no sub or sid",
+			typ:     "logout+jwt",
+			payload: claims(events),
+			config:  Config{ClientID: "client1"},
+			wantErr: true,
+		},
+		{
+			name:    "nonce",
+			typ:     "logout+jwt",
+			payload: claims(`,"sub":"user1","nonce":"n"` + events),
+			config:  Config{ClientID: "client1"},
+			wantErr: true,
+		},
+		{
+			name:    "no events",
+			typ:     "logout+jwt",
+			payload: claims(`,"sub":"user1"`),
+			config:  Config{ClientID: "client1"},
+			wantErr: true,
+		},
+		{
+			name:    "other event",
+			typ:     "logout+jwt",
+			payload: claims(`,"sub":"user1","events":{"http://schemas.openid.net/event/other":{}}`),
+			config:  Config{ClientID: "client1"},
+			wantErr: true,
+		},
+		{
+			name:    "event not an object",
+			typ:     "logout+jwt",
+			payload: claims(`,"sub":"user1","events":{"http://schemas.openid.net/event/backchannel-logout":"yes"}`),
+			config:  Config{ClientID: "client1"},
+			wantErr: true,
+		},
+		{
+			name:    "no jti",
+			typ:     "logout+jwt",
+			payload: `{"iss":"https://foo","aud":"client1","sub":"user1","exp":` + exp + `,"iat":` + iat + events + `}`,
+			config:  Config{ClientID: "client1"},
+			wantErr: true,
+		},
+		{
+			name:    "no iat",
+			typ:     "logout+jwt",
+			payload: `{"iss":"https://foo","aud":"client1","sub":"user1","jti":"abc","exp":` + exp + events + `}`,
+			config:  Config{ClientID: "client1"},
+			wantErr: true,
+		},
+		{
+			name:    "expired",
+			typ:     "logout+jwt",
+			payload: claims(`,"sub":"user1"` + events),
+			config: Config{
+				ClientID: "client1",
+				Now:      func() time.Time { return now.Add(time.Hour) },
+			},
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			typ:     "logout+jwt",
+			payload: claims(`,"sub":"user1"` + events),
+			config:  Config{ClientID: "client2"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong issuer",
+			typ:     "logout+jwt",
+			payload: strings.Replace(claims(`,"sub":"user1"`+events), "https://foo", "https://bar", 1),
+			config:  Config{ClientID: "client1"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			key := newRSAKey(t)
+			config := test.config
+			if config.Now == nil {
+				config.Now = func() time.Time { return now }
+			}
+			idTokenVerifier := NewVerifier("https://foo", &StaticKeySet{PublicKeys: []crypto.PublicKey{key.pub}}, &config)
+			v := NewLogoutTokenVerifier(idTokenVerifier, nil)
+			token, err := v.Verify(context.Background(), key.signWithType(t, []byte(test.payload), test.typ))
+			if err != nil {
+				if !test.wantErr {
+					t.Fatalf("Verify() failed: %v", err)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatalf("Verify(): expected error")
+			}
+			if token.Issuer != "https://foo" || token.JWTID != "abc" ||
+				token.Subject != test.want.Subject || token.SessionID != test.want.SessionID {
+				t.Errorf("unexpected token %+v", token)
+			}
+			if !token.Expiry.Equal(now.Add(2*time.Minute)) || !token.IssuedAt.Equal(now.Add(-time.Minute)) {
+				t.Errorf("unexpected token times, exp=%v, iat=%v", token.Expiry, token.IssuedAt)
+			}
+		})
+	}
+}
+
+func TestLogoutTokenVerifierReplay(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	newToken := func(key *signingKey, jti string) string {
+		payload, err := json.Marshal(map[string]interface{}{
+			"iss":    "https://foo",
+			"aud":    "client1",
+			"sid":    "session1",
+			"jti":    jti,
+			"iat":    now.Unix(),
+			"exp":    now.Add(2 * time.Minute).Unix(),
+			"events": map[string]interface{}{backChannelLogoutEvent: map[string]interface{}{}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key.signWithType(t, payload, logoutTokenType)
+	}
+
+	key := newRSAKey(t)
+	config := &Config{ClientID: "client1", Now: func() time.Time { return now }}
+	idTokenVerifier := NewVerifier("https://foo", &StaticKeySet{PublicKeys: []crypto.PublicKey{key.pub}}, config)
+	v := NewLogoutTokenVerifier(idTokenVerifier, nil)
+
+	// Tokens with an unknown signature don't consume their jti.
+	if _, err := v.Verify(context.Background(), newToken(newRSAKey(t), "abc")); err == nil {
+		t.Fatalf("expected error for token signed by a different key")
+	}
+	if _, err := v.Verify(context.Background(), newToken(key, "abc")); err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	if _, err := v.Verify(context.Background(), newToken(key, "abc")); err == nil {
+		t.Errorf("expected error for replayed token")
+	}
+	if _, err := v.Verify(context.Background(), newToken(key, "def")); err != nil {
+		t.Errorf("Verify() for a new jti failed: %v", err)
+	}
+}
+
+type failingReplayCache struct{}
+
+func (failingReplayCache) Add(ctx context.Context, id string, expiry time.Time) (bool, error) {
+	return false, errors.New("storage unavailable")
+}
+
+func (failingReplayCache) Remove(ctx context.Context, id string) error {
+	return errors.New("storage unavailable")
+}
+
+func TestMemoryReplayCache(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	c := &MemoryReplayCache{Now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	if added, err := c.Add(ctx, "a", now.Add(time.Minute)); err != nil || !added {
+		t.Fatalf("Add() = %t, %v, want true", added, err)
+	}
+	if added, err := c.Add(ctx, "a", now.Add(time.Minute)); err != nil || added {
+		t.Fatalf("Add() for recorded id = %t, %v, want false", added, err)
+	}
+	if err := c.Remove(ctx, "a"); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	if added, err := c.Add(ctx, "a", now.Add(time.Minute)); err != nil || !added {
+		t.Fatalf("Add() for removed id = %t, %v, want true", added, err)
+	}
+	if err := c.Remove(ctx, "b"); err != nil {
+		t.Fatalf("Remove() for unknown id failed: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if added, err := c.Add(ctx, "a", now.Add(time.Minute)); err != nil || !added {
+		t.Fatalf("Add() for expired id = %t, %v, want true", added, err)
+	}
+}
+
+func TestBackChannelLogoutHandler(t *testing.T) {
+	now := time.Now()
+	key := newRSAKey(t)
+	newToken := func(jti string) string {
+		payload, err := json.Marshal(map[string]interface{}{
+			"iss":    "https://foo",
+			"aud":    "client1",
+			"sid":    "session1",
+			"jti":    jti,
+			"iat":    now.Unix(),
+			"exp":    now.Add(2 * time.Minute).Unix(),
+			"events": map[string]interface{}{backChannelLogoutEvent: map[string]interface{}{}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key.signWithType(t, payload, logoutTokenType)
+	}
+	idTokenVerifier := NewVerifier("https://foo", &StaticKeySet{PublicKeys: []crypto.PublicKey{key.pub}}, &Config{ClientID: "client1"})
+
+	tests := []struct {
+		name        string
+		method      string
+		form        url.Values
+		replayCache ReplayCache
+		logoutErr   error
+		wantStatus  int
+		wantLogout  bool
+	}{
+		{
+			name:       "logout",
+			method:     http.MethodPost,
+			form:       url.Values{"logout_token": {newToken("a")}},
+			wantStatus: http.StatusOK,
+			wantLogout: true,
+		},
+		{
+			name:       "logout failed",
+			method:     http.MethodPost,
+			form:       url.Values{"logout_token": {newToken("b")}},
+			logoutErr:  errors.New("session store unavailable"),
+			wantStatus: http.StatusBadRequest,
+			wantLogout: true,
+		},
+		{
+			name:       "missing token",
+			method:     http.MethodPost,
+			form:       url.Values{},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid token",
+			method:     http.MethodPost,
+			form:       url.Values{"logout_token": {"not-a-jwt"}},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "replay cache failure",
+			method:      http.MethodPost,
+			form:        url.Values{"logout_token": {newToken("c")}},
+			replayCache: failingReplayCache{},
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:       "get request",
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gotToken *LogoutToken
+			h := BackChannelLogoutHandler(NewLogoutTokenVerifier(idTokenVerifier, test.replayCache),
+				func(ctx context.Context, token *LogoutToken) error {
+					gotToken = token
+					return test.logoutErr
+				})
+
+			r := httptest.NewRequest(test.method, "/backchannel-logout", strings.NewReader(test.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != test.wantStatus {
+				t.Errorf("unexpected status code, got=%d, want=%d", w.Code, test.wantStatus)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-store" {
+				t.Errorf("unexpected Cache-Control header %q", got)
+			}
+			if test.wantLogout {
+				if gotToken == nil || gotToken.SessionID != "session1" {
+					t.Errorf("expected logout callback with session1, got %+v", gotToken)
+				}
+			} else if gotToken != nil {
+				t.Errorf("unexpected logout callback with %+v", gotToken)
+			}
+			if w.Code == http.StatusBadRequest {
+				var resp struct {
+					Code string `json:"error"`
+				}
+				if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Code != ErrorInvalidRequest {
+					t.Errorf("expected invalid_request error response, got %q, %v", resp.Code, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBackChannelLogoutHandlerRetry(t *testing.T) {
+	now := time.Now()
+	key := newRSAKey(t)
+	payload, err := json.Marshal(map[string]interface{}{
+		"iss":    "https://foo",
+		"aud":    "client1",
+		"sid":    "session1",
+		"jti":    "a",
+		"iat":    now.Unix(),
+		"exp":    now.Add(2 * time.Minute).Unix(),
+		"events": map[string]interface{}{backChannelLogoutEvent: map[string]interface{}{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	form := url.Values{"logout_token": {key.signWithType(t, payload, logoutTokenType)}}
+	idTokenVerifier := NewVerifier("https://foo", &StaticKeySet{PublicKeys: []crypto.PublicKey{key.pub}}, &Config{ClientID: "client1"})
+
+	logoutErr := errors.New("session store unavailable")
+	h := BackChannelLogoutHandler(NewLogoutTokenVerifier(idTokenVerifier, nil),
+		func(ctx context.Context, token *LogoutToken) error {
+			return logoutErr
+		})
+	post := func() int {
+		r := httptest.NewRequest(http.MethodPost, "/backchannel-logout", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := post(); code != http.StatusBadRequest {
+		t.Fatalf("unexpected status code for failed logout, got=%d, want=%d", code, http.StatusBadRequest)
+	}
+	// The provider retries the same token once logout succeeds.
+	logoutErr = nil
+	if code := post(); code != http.StatusOK {
+		t.Fatalf("unexpected status code for retried token, got=%d, want=%d", code, http.StatusOK)
+	}
+	// Once logout succeeded, the token is a replay.
+	if code := post(); code != http.StatusBadRequest {
+		t.Errorf("unexpected status code for replayed token, got=%d, want=%d", code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,146 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache persists responses fetched from a provider, such as its discovery
+// document and key set, so they can be reused across processes. This lets
+// short-lived programs avoid network requests when a previous run already
+// fetched the same values.
+//
+// Keys are the URLs the responses were fetched from. Only responses that
+// advertise a lifetime through Cache-Control or Expires headers are stored, and
+// entries are only used until their expiry.
+//
+// Implementations must be safe for concurrent use.
+type Cache interface {
+	// Get returns the entry stored for a key, or nil if there's no entry.
+	Get(ctx context.Context, key string) (*CacheEntry, error)
+	// Set stores an entry for a key, replacing any existing entry.
+	Set(ctx context.Context, key string, entry *CacheEntry) error
+}
+
+// CacheEntry is a response stored in a Cache.
+type CacheEntry struct {
+	// Data is the raw body of the response.
+	Data []byte `json:"data"`
+	// Expiry is when the response becomes stale.
+	Expiry time.Time `json:"expiry"`
+}
+
+// CacheContext returns a new Context that carries the provided Cache.
+//
+// NewProvider and NewRemoteKeySet consult the cache before making requests and
+// populate it with the responses they fetch. Key sets also load stale keys
+// within their StaleGracePeriod, which are refreshed in the background, so
+// restarts can verify tokens while the jwks_uri is unreachable. Key sets created
+// by a Provider use the cache the provider was created with.
+//
+//	cache := &oidc.FileCache{Dir: filepath.Join(os.Getenv("HOME"), ".cache", "myapp")}
+//	ctx := oidc.CacheContext(parentContext, cache)
+//
+//	// Returns the cached provider metadata if it's still fresh.
+//	provider, err := oidc.NewProvider(ctx, "https://accounts.example.com")
+//
+// Caching is best effort. Errors returned by the cache are ignored, and values
+// are fetched from the network instead.
+func CacheContext(ctx context.Context, cache Cache) context.Context {
+	return context.WithValue(ctx, cacheKey, cache)
+}
+
+func getCache(ctx context.Context) Cache {
+	if c, ok := ctx.Value(cacheKey).(Cache); ok {
+		return c
+	}
+	return nil
+}
+
+// cacheLoad returns the entry cached for a key if it's still fresh, or stale by
+// less than the grace period.
+func cacheLoad(ctx context.Context, cache Cache, key string, now time.Time, grace time.Duration) *CacheEntry {
+	if cache == nil {
+		return nil
+	}
+	entry, err := cache.Get(ctx, key)
+	if err != nil || entry == nil || !now.Before(entry.Expiry.Add(grace)) {
+		return nil
+	}
+	return entry
+}
+
+// cacheStore stores data for a key. Data without an expiry isn't cached.
+func cacheStore(ctx context.Context, cache Cache, key string, data []byte, expiry time.Time) {
+	if cache == nil || expiry.IsZero() {
+		return
+	}
+	cache.Set(ctx, key, &CacheEntry{Data: data, Expiry: expiry})
+}
+
+// FileCache is a Cache that stores each entry as a file in a directory.
+type FileCache struct {
+	// Dir is the directory entries are stored in. It's created on the first
+	// write if it doesn't exist.
+	Dir string
+}
+
+// path returns the file used to store a key. Keys are hashed since they're
+// URLs, which aren't valid file names.
+func (f *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(f.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// Get reads the entry stored for a key.
+func (f *FileCache) Get(ctx context.Context, key string) (*CacheEntry, error) {
+	data, err := os.ReadFile(f.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("oidc: reading cache entry: %v", err)
+	}
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("oidc: decoding cache entry: %v", err)
+	}
+	return &entry, nil
+}
+
+// Set writes the entry for a key. The file is replaced atomically, so
+// concurrent readers never observe a partial entry.
+func (f *FileCache) Set(ctx context.Context, key string, entry *CacheEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("oidc: encoding cache entry: %v", err)
+	}
+	if err := os.MkdirAll(f.Dir, 0o700); err != nil {
+		return fmt.Errorf("oidc: creating cache directory: %v", err)
+	}
+	tmp, err := os.CreateTemp(f.Dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("oidc: creating cache entry: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("oidc: writing cache entry: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("oidc: writing cache entry: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path(key)); err != nil {
+		return fmt.Errorf("oidc: writing cache entry: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package oidc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+)
+
+func TestFileCache(t *testing.T) {
+	ctx := context.Background()
+	c := &FileCache{Dir: t.TempDir() + "/cache"}
+
+	entry, err := c.Get(ctx, "https://example.com/keys")
+	if err != nil {
+		t.Fatalf("get missing entry: %v", err)
+	}
+	if entry != nil {
+		t.Fatalf("expected no entry, got %v", entry)
+	}
+
+	want := &CacheEntry{
+		Data:   []byte(`{"keys":[]}`),
+		Expiry: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if err := c.Set(ctx, "https://example.com/keys", want); err != nil {
+		t.Fatalf("set entry: %v", err)
+	}
+	got, err := c.Get(ctx, "https://example.com/keys")
+	if err != nil {
+		t.Fatalf("get entry: %v", err)
+	}
+	if got == nil || !bytes.Equal(got.Data, want.Data) || !got.Expiry.Equal(want.Expiry) {
+		t.Errorf("get entry, got=%v, want=%v", got, want)
+	}
+
+	if entry, err := c.Get(ctx, "https://example.com/other"); err != nil || entry != nil {
+		t.Errorf("expected no entry for different key, got=%v, err=%v", entry, err)
+	}
+}
+
+func TestProviderCache(t *testing.T) {
+	key := newRSAKey(t)
+	requests := 0
+
+	var issuer string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", "max-age=3600")
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer":%q,"jwks_uri":%q}`, issuer, issuer+"/keys")
+		case "/keys":
+			(&keyServer{keys: jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.jwk()}}}).ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	issuer = s.URL
+
+	ctx := CacheContext(context.Background(), &FileCache{Dir: t.TempDir()})
+	token := key.sign(t, []byte(`{"iss":"`+issuer+`","aud":"client","exp":`+fmt.Sprint(time.Now().Add(time.Hour).Unix())+`}`))
+
+	verify := func() {
+		t.Helper()
+		p, err := NewProvider(ctx, issuer)
+		if err != nil {
+			t.Fatalf("new provider: %v", err)
+		}
+		if _, err := p.Verifier(&Config{ClientID: "client"}).Verify(ctx, token); err != nil {
+			t.Fatalf("verify: %v", err)
+		}
+	}
+
+	verify()
+	if requests != 2 {
+		t.Errorf("expected discovery and keys to be fetched, got %d requests", requests)
+	}
+
+	// A second run is served from the cache.
+	verify()
+	if requests != 2 {
+		t.Errorf("expected responses to be cached, got %d requests", requests)
+	}
+}
+
+func TestProviderCacheExpired(t *testing.T) {
+	requests := 0
+	var issuer string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintf(w, `{"issuer":%q}`, issuer)
+	}))
+	defer s.Close()
+	issuer = s.URL
+
+	cache := &FileCache{Dir: t.TempDir()}
+	ctx := CacheContext(context.Background(), cache)
+	wellKnown := issuer + "/.well-known/openid-configuration"
+
+	// Stale entries are ignored.
+	stale := &CacheEntry{
+		Data:   []byte(`{"issuer":"https://stale.example.com"}`),
+		Expiry: time.Now().Add(-time.Minute),
+	}
+	if err := cache.Set(ctx, wellKnown, stale); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewProvider(ctx, issuer); err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("expected stale entry to be refetched, got %d requests", requests)
+	}
+
+	// Responses without a lifetime aren't cached.
+	entry, err := cache.Get(ctx, wellKnown)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry == nil || !bytes.Equal(entry.Data, stale.Data) {
+		t.Errorf("expected cache entry to be unchanged, got %v", entry)
+	}
+}
+
+func TestRemoteKeySetStaleCache(t *testing.T) {
+	key := newRSAKey(t)
+	jws, err := jose.ParseSigned(key.sign(t, []byte("a secret")), allAlgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.jwk()}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The jwks_uri is unreachable, as if restarting during an outage.
+	s := httptest.NewServer(http.NotFoundHandler())
+	jwksURL := s.URL + "/keys"
+	s.Close()
+
+	cache := &FileCache{Dir: t.TempDir()}
+	ctx := CacheContext(context.Background(), cache)
+	stale := &CacheEntry{Data: data, Expiry: time.Now().Add(-10 * time.Minute)}
+	if err := cache.Set(ctx, jwksURL, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	// Stale keys within the default grace period are used.
+	if _, err := NewRemoteKeySet(ctx, jwksURL).verify(ctx, jws); err != nil {
+		t.Errorf("failed to verify signature with stale cached keys: %v", err)
+	}
+	config := &RemoteKeySetConfig{StaleGracePeriod: 20 * time.Minute}
+	if _, err := config.NewRemoteKeySet(ctx, jwksURL).verify(ctx, jws); err != nil {
+		t.Errorf("failed to verify signature with stale cached keys: %v", err)
+	}
+
+	// The configured grace period applies to cached keys.
+	config = &RemoteKeySetConfig{StaleGracePeriod: 5 * time.Minute}
+	if _, err := config.NewRemoteKeySet(ctx, jwksURL).verify(ctx, jws); err == nil {
+		t.Errorf("incorrectly verified signature with cached keys past the grace period")
+	}
+}
+
+func TestCacheContextNesting(t *testing.T) {
+	cache := &FileCache{Dir: t.TempDir()}
+	ctx := InsecureIssuerURLContext(context.Background(), "https://example.com")
+	ctx = CacheContext(ctx, cache)
+	ctx = DiscoveryLocationsContext(ctx, OAuthAuthorizationServer)
+
+	if got := getCache(ctx); got != cache {
+		t.Errorf("getCache() returned unexpected cache %v", got)
+	}
+	if got, _ := ctx.Value(issuerURLKey).(string); got != "https://example.com" {
+		t.Errorf("issuer URL shadowed by other context values, got %q", got)
+	}
+}
@@ -0,0 +1,187 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+)
+
+// Client authentication methods for requests to the provider's endpoints.
+//
+// See: https://openid.net/specs/openid-connect-core-1_0.html#ClientAuthentication
+const (
+	ClientSecretBasic = "client_secret_basic" // HTTP Basic authentication with the client secret
+	ClientSecretPost  = "client_secret_post"  // Client secret in the request body
+	PrivateKeyJWT     = "private_key_jwt"     // JWT assertion signed with the client's private key
+	ClientAuthNone    = "none"                // Client ID only, for public clients
+)
+
+// clientAssertionType is the client_assertion_type for JWT client assertions.
+const clientAssertionType = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
+// ClientAuth holds the credentials a client uses to authenticate to the provider's
+// endpoints, such as the introspection and revocation endpoints.
+type ClientAuth struct {
+	// ClientID is the ID of the client.
+	ClientID string
+	// ClientSecret is used by the client_secret_basic and client_secret_post methods.
+	ClientSecret string
+
+	// Method is the client authentication method. If empty, this defaults to
+	// client_secret_basic if ClientSecret is set, private_key_jwt if PrivateKey is
+	// set, and none otherwise. If the provider's metadata indicates the endpoint
+	// only accepts client_secret_post, that is used for client secrets instead.
+	Method string
+
+	// PrivateKey signs client assertions for the private_key_jwt method. Supported
+	// types are *rsa.PrivateKey, *ecdsa.PrivateKey and ed25519.PrivateKey.
+	PrivateKey crypto.PrivateKey
+	// KeyID is the kid header of client assertions, if set.
+	KeyID string
+	// SigningAlg is the algorithm used to sign client assertions. If empty, this
+	// defaults to RS256 for RSA keys, ES256, ES384 or ES512 for ECDSA keys
+	// depending on the curve, and EdDSA for Ed25519 keys.
+	SigningAlg string
+
+	// Time function used for client assertions. Defaults to time.Now
+	Now func() time.Time
+}
+
+// method returns the authentication method to use for an endpoint which supports
+// the provided methods. An empty list means the supported methods are unknown.
+func (c *ClientAuth) method(supported []string) string {
+	switch {
+	case c.Method != "":
+		return c.Method
+	case c.ClientSecret != "":
+		if len(supported) > 0 && !contains(supported, ClientSecretBasic) && contains(supported, ClientSecretPost) {
+			return ClientSecretPost
+		}
+		return ClientSecretBasic
+	case c.PrivateKey != nil:
+		return PrivateKeyJWT
+	default:
+		return ClientAuthNone
+	}
+}
+
+// newRequest creates a form POST request to an endpoint, authenticated using the
+// client's credentials. supportedMethods are the authentication methods the
+// endpoint supports according to the provider's metadata.
+func (c *ClientAuth) newRequest(endpoint string, form url.Values, supportedMethods []string) (*http.Request, error) {
+	if c == nil {
+		return nil, errors.New("oidc: no client authentication provided")
+	}
+	method := c.method(supportedMethods)
+	switch method {
+	case ClientSecretBasic:
+	case ClientSecretPost:
+		form.Set("client_id", c.ClientID)
+		form.Set("client_secret", c.ClientSecret)
+	case PrivateKeyJWT:
+		assertion, err := c.clientAssertion(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		form.Set("client_id", c.ClientID)
+		form.Set("client_assertion_type", clientAssertionType)
+		form.Set("client_assertion", assertion)
+	case ClientAuthNone:
+		form.Set("client_id", c.ClientID)
+	default:
+		return nil, fmt.Errorf("oidc: unsupported client authentication method %q", c.Method)
+	}
+
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: create POST request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if method == ClientSecretBasic {
+		// The credentials are form encoded before being used for basic auth.
+		//
+		// See: https://www.rfc-editor.org/rfc/rfc6749#section-2.3.1
+		req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+	return req, nil
+}
+
+// clientAssertion returns a signed JWT authenticating the client to an endpoint.
+//
+// See: https://www.rfc-editor.org/rfc/rfc7523#section-3
+func (c *ClientAuth) clientAssertion(audience string) (string, error) {
+	if c.PrivateKey == nil {
+		return "", errors.New("oidc: private_key_jwt client authentication requires a private key")
+	}
+	alg := c.SigningAlg
+	if alg == "" {
+		switch key := c.PrivateKey.(type) {
+		case *rsa.PrivateKey:
+			alg = RS256
+		case *ecdsa.PrivateKey:
+			switch key.Curve {
+			case elliptic.P256():
+				alg = ES256
+			case elliptic.P384():
+				alg = ES384
+			case elliptic.P521():
+				alg = ES512
+			}
+		case ed25519.PrivateKey:
+			alg = EdDSA
+		}
+		if alg == "" {
+			return "", fmt.Errorf("oidc: unsupported private key type %T", c.PrivateKey)
+		}
+	}
+
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+	jti, err := randomString()
+	if err != nil {
+		return "", err
+	}
+	nowTime := now()
+	payload, err := json.Marshal(struct {
+		Issuer   string `json:"iss"`
+		Subject  string `json:"sub"`
+		Audience string `json:"aud"`
+		JWTID    string `json:"jti"`
+		Expiry   int64  `json:"exp"`
+		IssuedAt int64  `json:"iat"`
+	}{
+		Issuer:   c.ClientID,
+		Subject:  c.ClientID,
+		Audience: audience,
+		JWTID:    jti,
+		Expiry:   nowTime.Add(5 * time.Minute).Unix(),
+		IssuedAt: nowTime.Unix(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("oidc: marshal client assertion: %v", err)
+	}
+
+	key := jose.JSONWebKey{Key: c.PrivateKey, KeyID: c.KeyID, Algorithm: alg}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.SignatureAlgorithm(alg), Key: key}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return "", fmt.Errorf("oidc: create client assertion signer: %v", err)
+	}
+	jws, err := signer.Sign(payload)
+	if err != nil {
+		return "", fmt.Errorf("oidc: sign client assertion: %v", err)
+	}
+	return jws.CompactSerialize()
+}
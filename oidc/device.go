@@ -0,0 +1,95 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/oauth2"
+)
+
+// DeviceFlow implements the OAuth 2.0 Device Authorization Grant for OpenID
+// Connect, letting devices without a browser or with limited input, such as CLIs,
+// log users in on another device. The ID Token returned by the token endpoint is
+// verified.
+//
+//	flow := provider.DeviceFlow(&oauth2.Config{
+//		ClientID: clientID,
+//		Scopes:   []string{oidc.ScopeOpenID, "profile", "email"},
+//	}, &oidc.Config{})
+//
+//	auth, err := flow.DeviceAuth(ctx)
+//	if err != nil {
+//		// handle error
+//	}
+//	fmt.Printf("Visit %s and enter the code %s\n", auth.VerificationURI, auth.UserCode)
+//
+//	result, err := flow.Exchange(ctx, auth)
+//	if err != nil {
+//		// handle error
+//	}
+//	// Use result.IDToken and result.Token.
+//
+// See: https://www.rfc-editor.org/rfc/rfc8628
+type DeviceFlow struct {
+	config   *oauth2.Config
+	verifier *IDTokenVerifier
+}
+
+// DeviceFlow returns a device authorization flow for the provider.
+//
+// If the oauth2 config doesn't specify an endpoint, the provider's endpoint is used.
+// If the verifier config doesn't specify a ClientID and SkipClientIDCheck isn't set,
+// the ClientID of the oauth2 config is used.
+func (p *Provider) DeviceFlow(oauth2Config *oauth2.Config, config *Config) *DeviceFlow {
+	oc, verifier := p.flowConfig(oauth2Config, config)
+	return &DeviceFlow{config: oc, verifier: verifier}
+}
+
+// DeviceResult is the result of a successful device authorization flow.
+type DeviceResult struct {
+	// Token is the token response of the provider.
+	Token *oauth2.Token
+	// IDToken is the verified ID Token.
+	IDToken *IDToken
+	// RawIDToken is the encoded ID Token, for instance to use as an id_token_hint.
+	RawIDToken string
+}
+
+// DeviceAuth starts the flow by requesting a device code from the provider's
+// device authorization endpoint.
+//
+// The user must be shown the response's VerificationURI and UserCode. If set,
+// VerificationURIComplete includes the user code and is suitable for display as
+// a QR code. Additional options are added to the request.
+func (f *DeviceFlow) DeviceAuth(ctx context.Context, opts ...oauth2.AuthCodeOption) (*oauth2.DeviceAuthResponse, error) {
+	if f.config.Endpoint.DeviceAuthURL == "" {
+		return nil, errors.New("oidc: provider has no device authorization endpoint")
+	}
+	auth, err := f.config.DeviceAuth(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: device authorization request failed: %w", err)
+	}
+	return auth, nil
+}
+
+// Exchange polls the provider's token endpoint until the user has approved or
+// denied the request, the device code expires, or the context is canceled. The ID
+// Token in the response is then verified.
+//
+// Polling waits for the interval requested by the provider, and backs off when the
+// provider responds with slow_down.
+//
+// The context is used for the token requests and any requests to the provider's
+// key set. Use ClientContext to provide a custom HTTP client.
+func (f *DeviceFlow) Exchange(ctx context.Context, auth *oauth2.DeviceAuthResponse, opts ...oauth2.AuthCodeOption) (*DeviceResult, error) {
+	token, err := f.config.DeviceAccessToken(ctx, auth, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: failed to exchange device code: %w", err)
+	}
+	idToken, rawIDToken, err := verifyTokenResponse(ctx, f.verifier, token)
+	if err != nil {
+		return nil, err
+	}
+	return &DeviceResult{Token: token, IDToken: idToken, RawIDToken: rawIDToken}, nil
+}
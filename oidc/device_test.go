@@ -0,0 +1,178 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"golang.org/x/oauth2"
+)
+
+// deviceServer is a minimal provider that issues ID Tokens for the device
+// authorization flow.
+type deviceServer struct {
+	t       *testing.T
+	baseURL string
+	key     *signingKey
+
+	// Number of token requests answered with authorization_pending before the
+	// token is issued.
+	pending int
+	// If set, token requests are answered with this error.
+	tokenErr string
+	// Overrides the audience of the issued ID Token.
+	audience string
+}
+
+func (s *deviceServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                        s.baseURL,
+			"authorization_endpoint":        s.baseURL + "/auth",
+			"token_endpoint":                s.baseURL + "/token",
+			"device_authorization_endpoint": s.baseURL + "/device",
+			"jwks_uri":                      s.baseURL + "/keys",
+		})
+	case "/keys":
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{s.key.jwk()}})
+	case "/device":
+		if r.FormValue("client_id") != "test-client" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"device_code":               "test-device-code",
+			"user_code":                 "ABCD-EFGH",
+			"verification_uri":          s.baseURL + "/activate",
+			"verification_uri_complete": s.baseURL + "/activate?user_code=ABCD-EFGH",
+			"expires_in":                60,
+			"interval":                  1,
+		})
+	case "/token":
+		w.Header().Set("Content-Type", "application/json")
+		if r.FormValue("device_code") != "test-device-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		if s.tokenErr != "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": s.tokenErr})
+			return
+		}
+		if s.pending > 0 {
+			s.pending--
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"authorization_pending"}`))
+			return
+		}
+		aud := "test-client"
+		if s.audience != "" {
+			aud = s.audience
+		}
+		payload, err := json.Marshal(map[string]interface{}{
+			"iss": s.baseURL,
+			"sub": "test-user",
+			"aud": aud,
+			"exp": time.Now().Add(time.Hour).Unix(),
+			"iat": time.Now().Unix(),
+		})
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     s.key.sign(s.t, payload),
+		})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestDeviceFlow(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  deviceServer
+		wantErr bool
+	}{
+		{
+			name:   "approved after pending",
+			server: deviceServer{pending: 1},
+		},
+		{
+			name:    "access denied",
+			server:  deviceServer{tokenErr: "access_denied"},
+			wantErr: true,
+		},
+		{
+			name:    "wrong audience",
+			server:  deviceServer{audience: "other-client"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ds := test.server
+			ds.t = t
+			ds.key = newRSAKey(t)
+			s := httptest.NewServer(&ds)
+			defer s.Close()
+			ds.baseURL = s.URL
+
+			ctx := context.Background()
+			p, err := NewProvider(ctx, s.URL)
+			if err != nil {
+				t.Fatalf("NewProvider() failed: %v", err)
+			}
+			flow := p.DeviceFlow(&oauth2.Config{
+				ClientID: "test-client",
+				Scopes:   []string{ScopeOpenID},
+			}, &Config{})
+
+			auth, err := flow.DeviceAuth(ctx)
+			if err != nil {
+				t.Fatalf("DeviceAuth() failed: %v", err)
+			}
+			if auth.UserCode != "ABCD-EFGH" || auth.VerificationURI != s.URL+"/activate" ||
+				auth.VerificationURIComplete != s.URL+"/activate?user_code=ABCD-EFGH" {
+				t.Errorf("unexpected device authorization response %+v", auth)
+			}
+
+			result, err := flow.Exchange(ctx, auth)
+			if err != nil {
+				if !test.wantErr {
+					t.Fatalf("Exchange() failed: %v", err)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatalf("Exchange(): expected error")
+			}
+			if result.Token.AccessToken != "test-access-token" {
+				t.Errorf("unexpected access token %q", result.Token.AccessToken)
+			}
+			if result.IDToken.Subject != "test-user" || result.RawIDToken == "" {
+				t.Errorf("unexpected id token %+v", result.IDToken)
+			}
+		})
+	}
+}
+
+func TestDeviceFlowNoEndpoint(t *testing.T) {
+	p := (&ProviderConfig{
+		IssuerURL: "https://example.com",
+		TokenURL:  "https://example.com/token",
+	}).NewProvider(context.Background())
+	flow := p.DeviceFlow(&oauth2.Config{ClientID: "test-client"}, &Config{})
+	if _, err := flow.DeviceAuth(context.Background()); err == nil {
+		t.Errorf("expected error for provider without device authorization endpoint")
+	}
+}
@@ -0,0 +1,184 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"golang.org/x/oauth2"
+)
+
+// AuthCodeFlow implements the OpenID Connect Authorization Code Flow with PKCE. It
+// generates the state, nonce, and code verifier for each login, and validates the
+// ID Token returned by the token endpoint.
+//
+//	flow := provider.AuthCodeFlow(&oauth2.Config{
+//		ClientID:     clientID,
+//		ClientSecret: clientSecret,
+//		RedirectURL:  "https://example.com/callback",
+//		Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+//	}, &oidc.Config{})
+//
+//	http.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+//		authURL, state, err := flow.AuthCodeURL()
+//		if err != nil {
+//			// handle error
+//		}
+//		// Persist state for the callback, e.g. in an encrypted cookie.
+//		http.Redirect(w, r, authURL, http.StatusFound)
+//	})
+//
+//	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+//		// Load the state stored by the login handler.
+//		result, err := flow.Exchange(r.Context(), state, r.URL.Query())
+//		if err != nil {
+//			// handle error
+//		}
+//		// Use result.IDToken and result.Token.
+//	})
+//
+// See: https://openid.net/specs/openid-connect-core-1_0.html#CodeFlowAuth
+type AuthCodeFlow struct {
+	config   *oauth2.Config
+	verifier *IDTokenVerifier
+}
+
+// AuthCodeFlow returns an authorization code flow for the provider.
+//
+// If the oauth2 config doesn't specify an endpoint, the provider's endpoint is used.
+// If the verifier config doesn't specify a ClientID and SkipClientIDCheck isn't set,
+// the ClientID of the oauth2 config is used.
+func (p *Provider) AuthCodeFlow(oauth2Config *oauth2.Config, config *Config) *AuthCodeFlow {
+	oc, verifier := p.flowConfig(oauth2Config, config)
+	return &AuthCodeFlow{config: oc, verifier: verifier}
+}
+
+// flowConfig returns copies of the configs of a flow, with the provider's endpoint
+// and the oauth2 ClientID filled in, and a verifier for the flow's ID Tokens.
+func (p *Provider) flowConfig(oauth2Config *oauth2.Config, config *Config) (*oauth2.Config, *IDTokenVerifier) {
+	// Make copies so we don't modify the caller's values.
+	oc := &oauth2.Config{}
+	*oc = *oauth2Config
+	if oc.Endpoint.AuthURL == "" && oc.Endpoint.TokenURL == "" && oc.Endpoint.DeviceAuthURL == "" {
+		oc.Endpoint = p.Endpoint()
+	}
+	c := &Config{}
+	*c = *config
+	if c.ClientID == "" && !c.SkipClientIDCheck {
+		c.ClientID = oc.ClientID
+	}
+	return oc, p.Verifier(c)
+}
+
+// AuthCodeState holds the values generated for a single login attempt. It must be
+// stored between the authorization request and the callback, for instance in an
+// encrypted cookie or a server-side session, and must not be reused.
+type AuthCodeState struct {
+	// State is the value of the state parameter used to prevent CSRF.
+	State string `json:"state"`
+	// Nonce is the value the ID Token must contain to prevent replay attacks.
+	Nonce string `json:"nonce"`
+	// CodeVerifier is the PKCE code verifier sent during the token exchange.
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// AuthCodeResult is the result of a successful authorization code flow.
+type AuthCodeResult struct {
+	// Token is the token response of the provider.
+	Token *oauth2.Token
+	// IDToken is the verified ID Token.
+	IDToken *IDToken
+	// RawIDToken is the encoded ID Token, for instance to use as an id_token_hint.
+	RawIDToken string
+}
+
+// AuthCodeURL generates a new state, nonce, and PKCE code verifier, and returns the
+// URL of the provider's authorization endpoint to redirect the user to.
+//
+// Additional options, such as oauth2.AccessTypeOffline, are added to the URL.
+func (f *AuthCodeFlow) AuthCodeURL(opts ...oauth2.AuthCodeOption) (string, *AuthCodeState, error) {
+	state, err := randomString()
+	if err != nil {
+		return "", nil, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return "", nil, err
+	}
+	s := &AuthCodeState{
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: oauth2.GenerateVerifier(),
+	}
+	opts = append(opts, Nonce(s.Nonce), oauth2.S256ChallengeOption(s.CodeVerifier))
+	return f.config.AuthCodeURL(s.State, opts...), s, nil
+}
+
+// Exchange validates the parameters of the callback request against the state
+// returned by AuthCodeURL, exchanges the authorization code for a token, and
+// verifies the ID Token in the response.
+//
+// The context is used for the token request and any requests to the provider's
+// key set. Use ClientContext to provide a custom HTTP client.
+func (f *AuthCodeFlow) Exchange(ctx context.Context, state *AuthCodeState, params url.Values) (*AuthCodeResult, error) {
+	if state == nil || state.State == "" {
+		return nil, errors.New("oidc: no state for authorization code flow")
+	}
+	if errCode := params.Get("error"); errCode != "" {
+		if desc := params.Get("error_description"); desc != "" {
+			return nil, fmt.Errorf("oidc: authorization failed: %s: %s", errCode, desc)
+		}
+		return nil, fmt.Errorf("oidc: authorization failed: %s", errCode)
+	}
+	if params.Get("state") != state.State {
+		return nil, errors.New("oidc: state did not match")
+	}
+	code := params.Get("code")
+	if code == "" {
+		return nil, errors.New("oidc: callback did not contain an authorization code")
+	}
+
+	token, err := f.config.Exchange(ctx, code, oauth2.VerifierOption(state.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("oidc: failed to exchange token: %w", err)
+	}
+	idToken, rawIDToken, err := verifyTokenResponse(ctx, f.verifier, token, ExpectNonce(state.Nonce))
+	if err != nil {
+		return nil, err
+	}
+	return &AuthCodeResult{Token: token, IDToken: idToken, RawIDToken: rawIDToken}, nil
+}
+
+// verifyTokenResponse verifies the ID Token of a token response, and returns it
+// along with its encoded form.
+func verifyTokenResponse(ctx context.Context, verifier *IDTokenVerifier, token *oauth2.Token, opts ...VerifyOption) (*IDToken, string, error) {
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, "", errors.New("oidc: token response did not contain an id_token")
+	}
+	idToken, err := verifier.VerifyWithOptions(ctx, rawIDToken, opts...)
+	if err != nil {
+		return nil, "", fmt.Errorf("oidc: failed to verify id token: %w", err)
+	}
+	// The at_hash claim is optional for flows which return tokens from the token
+	// endpoint, but must be correct if present.
+	if idToken.AccessTokenHash != "" {
+		if err := idToken.VerifyAccessToken(token.AccessToken); err != nil {
+			return nil, "", fmt.Errorf("oidc: %v", err)
+		}
+	}
+	return idToken, rawIDToken, nil
+}
+
+// randomString returns a random URL safe value with 128 bits of entropy.
+func randomString() (string, error) {
+	b := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return "", fmt.Errorf("oidc: failed to generate random value: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,237 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"golang.org/x/oauth2"
+)
+
+// authCodeServer is a minimal provider that issues ID Tokens for the
+// authorization code flow.
+type authCodeServer struct {
+	t       *testing.T
+	baseURL string
+	key     *signingKey
+
+	// Values of the last authorization request.
+	nonce     string
+	challenge string
+
+	// Overrides for the issued tokens.
+	idTokenNonce string
+	atHash       string
+	noIDToken    bool
+}
+
+func (s *authCodeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 s.baseURL,
+			"authorization_endpoint": s.baseURL + "/auth",
+			"token_endpoint":         s.baseURL + "/token",
+			"jwks_uri":               s.baseURL + "/keys",
+		})
+	case "/keys":
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{s.key.jwk()}})
+	case "/token":
+		if r.FormValue("code") != "test-code" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		sum := sha256.Sum256([]byte(r.FormValue("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != s.challenge {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		resp := map[string]interface{}{
+			"access_token": "test-access-token",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+		}
+		if !s.noIDToken {
+			nonce := s.nonce
+			if s.idTokenNonce != "" {
+				nonce = s.idTokenNonce
+			}
+			claims := map[string]interface{}{
+				"iss":   s.baseURL,
+				"sub":   "test-user",
+				"aud":   "test-client",
+				"exp":   time.Now().Add(time.Hour).Unix(),
+				"iat":   time.Now().Unix(),
+				"nonce": nonce,
+			}
+			if s.atHash != "" {
+				claims["at_hash"] = s.atHash
+			}
+			payload, err := json.Marshal(claims)
+			if err != nil {
+				s.t.Fatal(err)
+			}
+			resp["id_token"] = s.key.sign(s.t, payload)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestAuthCodeFlow(t *testing.T) {
+	tests := []struct {
+		name    string
+		server  authCodeServer
+		params  func(state *AuthCodeState) url.Values
+		wantErr bool
+		// Optional check of the returned error.
+		checkErr func(err error) bool
+	}{
+		{
+			name: "good flow",
+		},
+		{
+			name:   "good at_hash",
+			server: authCodeServer{atHash: "WXSA1LYsphIZPxnnP-TMOg"},
+		},
+		{
+			name:    "bad at_hash",
+			server:  authCodeServer{atHash: "bad-hash"},
+			wantErr: true,
+		},
+		{
+			name:    "nonce mismatch",
+			server:  authCodeServer{idTokenNonce: "other-nonce"},
+			wantErr: true,
+			checkErr: func(err error) bool {
+				var errNonce *NonceMismatchError
+				return errors.As(err, &errNonce)
+			},
+		},
+		{
+			name:    "no id_token",
+			server:  authCodeServer{noIDToken: true},
+			wantErr: true,
+		},
+		{
+			name: "state mismatch",
+			params: func(state *AuthCodeState) url.Values {
+				return url.Values{"state": {"other-state"}, "code": {"test-code"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "authorization error",
+			params: func(state *AuthCodeState) url.Values {
+				return url.Values{"state": {state.State}, "error": {"access_denied"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "bad code",
+			params: func(state *AuthCodeState) url.Values {
+				return url.Values{"state": {state.State}, "code": {"bad-code"}}
+			},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			as := test.server
+			as.t = t
+			as.key = newRSAKey(t)
+			s := httptest.NewServer(&as)
+			defer s.Close()
+			as.baseURL = s.URL
+
+			ctx := context.Background()
+			provider, err := NewProvider(ctx, s.URL)
+			if err != nil {
+				t.Fatalf("NewProvider() failed: %v", err)
+			}
+			flow := provider.AuthCodeFlow(&oauth2.Config{
+				ClientID:    "test-client",
+				RedirectURL: "https://example.com/callback",
+				Scopes:      []string{ScopeOpenID},
+			}, &Config{})
+
+			authURL, state, err := flow.AuthCodeURL()
+			if err != nil {
+				t.Fatalf("AuthCodeURL() failed: %v", err)
+			}
+			if !strings.HasPrefix(authURL, s.URL+"/auth?") {
+				t.Fatalf("AuthCodeURL() returned unexpected URL %s", authURL)
+			}
+			u, err := url.Parse(authURL)
+			if err != nil {
+				t.Fatalf("parse auth URL: %v", err)
+			}
+			q := u.Query()
+			if q.Get("state") != state.State {
+				t.Errorf("auth URL state %q doesn't match %q", q.Get("state"), state.State)
+			}
+			if q.Get("code_challenge_method") != "S256" {
+				t.Errorf("auth URL has unexpected code_challenge_method %q", q.Get("code_challenge_method"))
+			}
+			as.nonce = q.Get("nonce")
+			as.challenge = q.Get("code_challenge")
+			if as.nonce != state.Nonce {
+				t.Errorf("auth URL nonce %q doesn't match %q", as.nonce, state.Nonce)
+			}
+
+			params := url.Values{"state": {state.State}, "code": {"test-code"}}
+			if test.params != nil {
+				params = test.params(state)
+			}
+			result, err := flow.Exchange(ctx, state, params)
+			if err != nil {
+				if !test.wantErr {
+					t.Fatalf("Exchange() failed: %v", err)
+				}
+				if test.checkErr != nil && !test.checkErr(err) {
+					t.Errorf("Exchange() returned unexpected error: %v", err)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatalf("Exchange(): expected error")
+			}
+			if result.IDToken.Subject != "test-user" {
+				t.Errorf("unexpected subject %q", result.IDToken.Subject)
+			}
+			if result.Token.AccessToken != "test-access-token" {
+				t.Errorf("unexpected access token %q", result.Token.AccessToken)
+			}
+			if result.RawIDToken == "" {
+				t.Errorf("expected raw ID Token")
+			}
+		})
+	}
+}
+
+func TestAuthCodeFlowUniqueState(t *testing.T) {
+	p := &Provider{authURL: "https://example.com/auth", tokenURL: "https://example.com/token"}
+	flow := p.AuthCodeFlow(&oauth2.Config{ClientID: "test-client"}, &Config{})
+	_, s1, err := flow.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, s2, err := flow.AuthCodeURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s1.State == s2.State || s1.Nonce == s2.Nonce || s1.CodeVerifier == s2.CodeVerifier {
+		t.Errorf("expected unique values for each login, got %+v and %+v", s1, s2)
+	}
+}
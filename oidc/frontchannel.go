@@ -0,0 +1,73 @@
+package oidc
+
+import (
+	"net/http"
+)
+
+// FrontChannelLogoutRequest holds the parameters of a front-channel logout request.
+type FrontChannelLogoutRequest struct {
+	// Issuer of the provider which sent the request. Empty if the provider didn't
+	// include session information.
+	Issuer string
+	// SessionID is the session at the provider which was terminated. This matches
+	// the sid claim of ID Tokens issued for the session. Empty if the provider
+	// didn't include session information.
+	SessionID string
+}
+
+// FrontChannelLogoutHandler returns an http.Handler for the front-channel logout
+// URI registered with the provider. The provider loads this URI in an iframe of the
+// user's browser when the user logs out at the provider.
+//
+// If the request includes the iss and sid parameters, the issuer must match the
+// provider's. Providers only include them if they support sessions, as indicated
+// by the FrontChannelLogoutSessionSupported metadata field, and the client
+// registered with frontchannel_logout_session_required. Otherwise logout must
+// terminate the session identified by the browser's cookies.
+//
+//	http.Handle("/frontchannel-logout", provider.FrontChannelLogoutHandler(
+//		func(w http.ResponseWriter, r *http.Request, req *oidc.FrontChannelLogoutRequest) error {
+//			if req.SessionID != "" {
+//				return sessions.DeleteBySessionID(r.Context(), req.SessionID)
+//			}
+//			return sessions.Delete(w, r)
+//		}))
+//
+// Responses aren't cached by the browser, as required by the specification.
+//
+// See: https://openid.net/specs/openid-connect-frontchannel-1_0.html#RPLogout
+func (p *Provider) FrontChannelLogoutHandler(logout func(w http.ResponseWriter, r *http.Request, req *FrontChannelLogoutRequest) error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-cache, no-store")
+		w.Header().Set("Pragma", "no-cache")
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		q := r.URL.Query()
+		req := &FrontChannelLogoutRequest{Issuer: q.Get("iss"), SessionID: q.Get("sid")}
+		// Either both parameters are included, or neither.
+		if (req.Issuer == "") != (req.SessionID == "") {
+			http.Error(w, "iss and sid parameters must be provided together", http.StatusBadRequest)
+			return
+		}
+		if req.Issuer != "" {
+			p.discoveryMu.RLock()
+			issuer := p.issuer
+			p.discoveryMu.RUnlock()
+			if req.Issuer != issuer {
+				http.Error(w, "logout request issued by a different provider", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if err := logout(w, r, req); err != nil {
+			http.Error(w, "logout failed", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+	})
+}
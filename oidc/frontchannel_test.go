@@ -0,0 +1,97 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFrontChannelLogoutHandler(t *testing.T) {
+	p := (&ProviderConfig{IssuerURL: "https://example.com"}).NewProvider(context.Background())
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		logoutErr  error
+		wantStatus int
+		wantLogout *FrontChannelLogoutRequest
+	}{
+		{
+			name:       "session",
+			method:     http.MethodGet,
+			target:     "/logout?iss=https%3A%2F%2Fexample.com&sid=session1",
+			wantStatus: http.StatusOK,
+			wantLogout: &FrontChannelLogoutRequest{Issuer: "https://example.com", SessionID: "session1"},
+		},
+		{
+			name:       "no session",
+			method:     http.MethodGet,
+			target:     "/logout",
+			wantStatus: http.StatusOK,
+			wantLogout: &FrontChannelLogoutRequest{},
+		},
+		{
+			name:       "wrong issuer",
+			method:     http.MethodGet,
+			target:     "/logout?iss=https%3A%2F%2Fother.example.com&sid=session1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "sid without iss",
+			method:     http.MethodGet,
+			target:     "/logout?sid=session1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "iss without sid",
+			method:     http.MethodGet,
+			target:     "/logout?iss=https%3A%2F%2Fexample.com",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "logout failed",
+			method:     http.MethodGet,
+			target:     "/logout?iss=https%3A%2F%2Fexample.com&sid=session1",
+			logoutErr:  errors.New("session store unavailable"),
+			wantStatus: http.StatusInternalServerError,
+			wantLogout: &FrontChannelLogoutRequest{Issuer: "https://example.com", SessionID: "session1"},
+		},
+		{
+			name:       "post request",
+			method:     http.MethodPost,
+			target:     "/logout",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var gotLogout *FrontChannelLogoutRequest
+			h := p.FrontChannelLogoutHandler(func(w http.ResponseWriter, r *http.Request, req *FrontChannelLogoutRequest) error {
+				gotLogout = req
+				return test.logoutErr
+			})
+
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(test.method, test.target, nil))
+
+			if w.Code != test.wantStatus {
+				t.Errorf("unexpected status code, got=%d, want=%d", w.Code, test.wantStatus)
+			}
+			if got := w.Header().Get("Cache-Control"); got != "no-cache, no-store" {
+				t.Errorf("unexpected Cache-Control header %q", got)
+			}
+			if got := w.Header().Get("Pragma"); got != "no-cache" {
+				t.Errorf("unexpected Pragma header %q", got)
+			}
+			switch {
+			case test.wantLogout == nil && gotLogout != nil:
+				t.Errorf("unexpected logout callback with %+v", gotLogout)
+			case test.wantLogout != nil && (gotLogout == nil || *gotLogout != *test.wantLogout):
+				t.Errorf("unexpected logout callback, got=%+v, want=%+v", gotLogout, test.wantLogout)
+			}
+		})
+	}
+}
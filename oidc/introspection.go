@@ -0,0 +1,221 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// introspectionJWTType is the typ header and media type of signed introspection
+// responses.
+const introspectionJWTType = "token-introspection+jwt"
+
+// IntrospectionResponse is the provider's response to a token introspection request.
+//
+// See: https://www.rfc-editor.org/rfc/rfc7662#section-2.2
+type IntrospectionResponse struct {
+	// Active indicates whether the token is currently active. All other fields are
+	// only meaningful if this is true.
+	Active bool
+	// Scopes associated with the token.
+	Scopes []string
+	// The client the token was issued to.
+	ClientID string
+	// Human readable identifier of the resource owner.
+	Username string
+	// Type of the token, for example "Bearer".
+	TokenType string
+	// Expiry of the token, or the zero value if unknown.
+	Expiry time.Time
+	// When the token was issued, or the zero value if unknown.
+	IssuedAt time.Time
+	// Time before which the token must not be used, or the zero value if unknown.
+	NotBefore time.Time
+	// Subject of the token, usually the resource owner.
+	Subject string
+	// Intended audience of the token.
+	Audience []string
+	// Issuer of the token.
+	Issuer string
+	// Unique identifier of the token.
+	JWTID string
+
+	// Raw JSON response.
+	claims []byte
+}
+
+// Claims unmarshals the raw JSON response into a provided struct, for instance to
+// access provider specific members.
+func (r *IntrospectionResponse) Claims(v interface{}) error {
+	if r.claims == nil {
+		return errors.New("oidc: claims not set")
+	}
+	return json.Unmarshal(r.claims, v)
+}
+
+type introspectionResponse struct {
+	Active    bool      `json:"active"`
+	Scope     string    `json:"scope"`
+	ClientID  string    `json:"client_id"`
+	Username  string    `json:"username"`
+	TokenType string    `json:"token_type"`
+	Expiry    *jsonTime `json:"exp"`
+	IssuedAt  *jsonTime `json:"iat"`
+	NotBefore *jsonTime `json:"nbf"`
+	Subject   string    `json:"sub"`
+	Audience  audience  `json:"aud"`
+	Issuer    string    `json:"iss"`
+	JWTID     string    `json:"jti"`
+}
+
+func parseIntrospectionResponse(body []byte) (*IntrospectionResponse, error) {
+	var resp introspectionResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("oidc: failed to decode introspection response: %v", err)
+	}
+	timeOf := func(t *jsonTime) time.Time {
+		if t == nil {
+			return time.Time{}
+		}
+		return time.Time(*t)
+	}
+	return &IntrospectionResponse{
+		Active:    resp.Active,
+		Scopes:    strings.Fields(resp.Scope),
+		ClientID:  resp.ClientID,
+		Username:  resp.Username,
+		TokenType: resp.TokenType,
+		Expiry:    timeOf(resp.Expiry),
+		IssuedAt:  timeOf(resp.IssuedAt),
+		NotBefore: timeOf(resp.NotBefore),
+		Subject:   resp.Subject,
+		Audience:  []string(resp.Audience),
+		Issuer:    resp.Issuer,
+		JWTID:     resp.JWTID,
+		claims:    body,
+	}, nil
+}
+
+// Introspect queries the provider's introspection endpoint for the state and
+// metadata of a token, authenticating with the client's credentials. The
+// tokenTypeHint, such as TokenTypeHintAccessToken, may be empty.
+//
+// Callers must check the Active field of the response before trusting the token.
+// Error responses from the provider are returned as an *OAuthError.
+//
+//	resp, err := provider.Introspect(ctx, &oidc.ClientAuth{
+//		ClientID:     clientID,
+//		ClientSecret: clientSecret,
+//	}, accessToken, oidc.TokenTypeHintAccessToken)
+//	if err != nil {
+//		// handle error
+//	}
+//	if !resp.Active {
+//		// reject token
+//	}
+//
+// See: https://www.rfc-editor.org/rfc/rfc7662
+func (p *Provider) Introspect(ctx context.Context, auth *ClientAuth, token, tokenTypeHint string) (*IntrospectionResponse, error) {
+	body, _, err := p.introspect(ctx, auth, token, tokenTypeHint, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	return parseIntrospectionResponse(body)
+}
+
+// IntrospectJWT is like Introspect, but requests a signed JWT response from the
+// provider and verifies it with the provider's key set. This protects the response
+// from tampering, for instance by intermediaries, and lets the response be stored
+// as a signed record.
+//
+// See: https://www.rfc-editor.org/rfc/rfc9701
+func (p *Provider) IntrospectJWT(ctx context.Context, auth *ClientAuth, token, tokenTypeHint string) (*IntrospectionResponse, error) {
+	body, contentType, err := p.introspect(ctx, auth, token, tokenTypeHint, "application/"+introspectionJWTType)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !isMediaType(mediaType, introspectionJWTType) {
+		return nil, fmt.Errorf("oidc: expected introspection response of type %s, got %q", introspectionJWTType, contentType)
+	}
+
+	rawJWT := strings.TrimSpace(string(body))
+	header, err := parseJWTHeader(rawJWT)
+	if err != nil {
+		return nil, err
+	}
+	if !isMediaType(header.Type, introspectionJWTType) {
+		return nil, fmt.Errorf("oidc: expected jwt with typ %q got %q", introspectionJWTType, header.Type)
+	}
+	payload, err := parseJWT(rawJWT)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: malformed jwt: %v", err)
+	}
+	var claims struct {
+		Issuer             string          `json:"iss"`
+		Audience           audience        `json:"aud"`
+		TokenIntrospection json.RawMessage `json:"token_introspection"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("oidc: failed to unmarshal claims: %v", err)
+	}
+
+	p.discoveryMu.RLock()
+	issuer := p.issuer
+	p.discoveryMu.RUnlock()
+	if claims.Issuer != issuer {
+		return nil, fmt.Errorf("oidc: introspection response issued by a different provider, expected %q got %q", issuer, claims.Issuer)
+	}
+	if !contains(claims.Audience, auth.ClientID) {
+		return nil, fmt.Errorf("oidc: expected audience %q got %q", auth.ClientID, claims.Audience)
+	}
+	if len(claims.TokenIntrospection) == 0 {
+		return nil, errors.New("oidc: introspection response has no token_introspection claim")
+	}
+
+	if _, err := verifySignature(ctx, p.remoteKeySet(), rawJWT, payload, p.signingAlgorithms(), "introspection response"); err != nil {
+		return nil, err
+	}
+	return parseIntrospectionResponse(claims.TokenIntrospection)
+}
+
+// introspect posts a token to the introspection endpoint, and returns the response
+// body and content type.
+func (p *Provider) introspect(ctx context.Context, auth *ClientAuth, token, tokenTypeHint, accept string) ([]byte, string, error) {
+	md := p.Metadata()
+	if md == nil || md.IntrospectionURL == "" {
+		return nil, "", errors.New("oidc: provider has no introspection endpoint")
+	}
+
+	form := url.Values{"token": {token}}
+	if tokenTypeHint != "" {
+		form.Set("token_type_hint", tokenTypeHint)
+	}
+	req, err := auth.newRequest(md.IntrospectionURL, form, md.IntrospectionEndpointAuthMethodsSupported)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Accept", accept)
+
+	resp, err := doRequest(ctx, req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("unable to read response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", newOAuthError(resp.StatusCode, body)
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
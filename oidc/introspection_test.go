@@ -0,0 +1,322 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+)
+
+// introspectionServer implements an introspection endpoint, which accepts the
+// token "good-token" from client "client1" with secret "secret1", or with client
+// assertions signed by clientKey.
+type introspectionServer struct {
+	t         *testing.T
+	issuer    string
+	key       *signingKey
+	clientKey *signingKey
+	// If set, signs responses instead of key.
+	responseKey *signingKey
+
+	// Authentication method the client used for the last request.
+	gotMethod string
+}
+
+func (s *introspectionServer) authenticate(r *http.Request) bool {
+	if id, secret, ok := r.BasicAuth(); ok {
+		s.gotMethod = ClientSecretBasic
+		return id == "client1" && secret == "secret1"
+	}
+	if r.PostFormValue("client_secret") != "" {
+		s.gotMethod = ClientSecretPost
+		return r.PostFormValue("client_id") == "client1" && r.PostFormValue("client_secret") == "secret1"
+	}
+	if assertion := r.PostFormValue("client_assertion"); assertion != "" {
+		s.gotMethod = PrivateKeyJWT
+		if r.PostFormValue("client_assertion_type") != clientAssertionType {
+			return false
+		}
+		jws, err := jose.ParseSigned(assertion, []jose.SignatureAlgorithm{s.clientKey.alg})
+		if err != nil {
+			return false
+		}
+		payload, err := jws.Verify(s.clientKey.pub)
+		if err != nil {
+			return false
+		}
+		var claims struct {
+			Issuer   string `json:"iss"`
+			Subject  string `json:"sub"`
+			Audience string `json:"aud"`
+			JWTID    string `json:"jti"`
+		}
+		if err := json.Unmarshal(payload, &claims); err != nil {
+			return false
+		}
+		return claims.Issuer == "client1" && claims.Subject == "client1" &&
+			claims.Audience == s.issuer+"/introspect" && claims.JWTID != ""
+	}
+	s.gotMethod = ClientAuthNone
+	return r.PostFormValue("client_id") == "client1"
+}
+
+func (s *introspectionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/keys":
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{s.key.jwk()}})
+	case "/introspect":
+		if !s.authenticate(r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client"}`))
+			return
+		}
+		resp := map[string]interface{}{"active": false}
+		if r.PostFormValue("token") == "good-token" {
+			resp = map[string]interface{}{
+				"active":     true,
+				"scope":      "read write",
+				"client_id":  "client1",
+				"username":   "jdoe",
+				"token_type": "Bearer",
+				"exp":        1700000000,
+				"iat":        1690000000,
+				"sub":        "user1",
+				"aud":        "https://api.example.com",
+				"iss":        s.issuer,
+				"extension":  "value",
+			}
+		}
+		if r.Header.Get("Accept") != "application/token-introspection+jwt" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(resp)
+			return
+		}
+		payload, err := json.Marshal(map[string]interface{}{
+			"iss":                 s.issuer,
+			"aud":                 "client1",
+			"iat":                 time.Now().Unix(),
+			"token_introspection": resp,
+		})
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		key := s.key
+		if s.responseKey != nil {
+			key = s.responseKey
+		}
+		w.Header().Set("Content-Type", "application/token-introspection+jwt")
+		w.Write([]byte(key.signWithType(s.t, payload, introspectionJWTType)))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newIntrospectionServer(t *testing.T) (*introspectionServer, *Provider) {
+	is := &introspectionServer{t: t, key: newRSAKey(t), clientKey: newECDSAKey(t)}
+	s := httptest.NewServer(is)
+	t.Cleanup(s.Close)
+	is.issuer = s.URL
+
+	config := &ProviderConfig{
+		IssuerURL:        s.URL,
+		JWKSURL:          s.URL + "/keys",
+		IntrospectionURL: s.URL + "/introspect",
+	}
+	return is, config.NewProvider(context.Background())
+}
+
+func TestIntrospect(t *testing.T) {
+	tests := []struct {
+		name       string
+		auth       ClientAuth
+		wantMethod string
+		wantErr    bool
+	}{
+		{
+			name:       "client_secret_basic",
+			auth:       ClientAuth{ClientID: "client1", ClientSecret: "secret1"},
+			wantMethod: ClientSecretBasic,
+		},
+		{
+			name:       "client_secret_post",
+			auth:       ClientAuth{ClientID: "client1", ClientSecret: "secret1", Method: ClientSecretPost},
+			wantMethod: ClientSecretPost,
+		},
+		{
+			name:       "none",
+			auth:       ClientAuth{ClientID: "client1"},
+			wantMethod: ClientAuthNone,
+		},
+		{
+			name:       "wrong secret",
+			auth:       ClientAuth{ClientID: "client1", ClientSecret: "secret2"},
+			wantMethod: ClientSecretBasic,
+			wantErr:    true,
+		},
+		{
+			name:    "unsupported method",
+			auth:    ClientAuth{ClientID: "client1", Method: "tls_client_auth"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			is, p := newIntrospectionServer(t)
+			resp, err := p.Introspect(context.Background(), &test.auth, "good-token", TokenTypeHintAccessToken)
+			if is.gotMethod != test.wantMethod {
+				t.Errorf("expected client authentication method %q, got %q", test.wantMethod, is.gotMethod)
+			}
+			if err != nil {
+				if !test.wantErr {
+					t.Fatalf("Introspect() failed: %v", err)
+				}
+				var oauthErr *OAuthError
+				if test.wantMethod != "" && (!errors.As(err, &oauthErr) || oauthErr.Code != ErrorInvalidClient) {
+					t.Errorf("expected invalid_client error, got %v", err)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatalf("Introspect(): expected error")
+			}
+			want := &IntrospectionResponse{
+				Active:    true,
+				Scopes:    []string{"read", "write"},
+				ClientID:  "client1",
+				Username:  "jdoe",
+				TokenType: "Bearer",
+				Expiry:    time.Unix(1700000000, 0),
+				IssuedAt:  time.Unix(1690000000, 0),
+				Subject:   "user1",
+				Audience:  []string{"https://api.example.com"},
+				Issuer:    is.issuer,
+				claims:    resp.claims,
+			}
+			if !reflect.DeepEqual(resp, want) {
+				t.Errorf("unexpected response, got=%+v, want=%+v", resp, want)
+			}
+			var claims struct {
+				Extension string `json:"extension"`
+			}
+			if err := resp.Claims(&claims); err != nil {
+				t.Fatalf("Claims() failed: %v", err)
+			}
+			if claims.Extension != "value" {
+				t.Errorf("unexpected extension claim %q", claims.Extension)
+			}
+		})
+	}
+}
+
+func TestIntrospectPrivateKeyJWT(t *testing.T) {
+	is, p := newIntrospectionServer(t)
+	auth := &ClientAuth{ClientID: "client1", PrivateKey: is.clientKey.priv}
+	resp, err := p.Introspect(context.Background(), auth, "good-token", "")
+	if err != nil {
+		t.Fatalf("Introspect() failed: %v", err)
+	}
+	if is.gotMethod != PrivateKeyJWT {
+		t.Errorf("expected client authentication method %q, got %q", PrivateKeyJWT, is.gotMethod)
+	}
+	if !resp.Active {
+		t.Errorf("expected active token")
+	}
+
+	auth.PrivateKey = newECDSAKey(t).priv
+	if _, err := p.Introspect(context.Background(), auth, "good-token", ""); err == nil {
+		t.Errorf("expected error for assertion signed by an unknown key")
+	}
+}
+
+func TestIntrospectInactive(t *testing.T) {
+	_, p := newIntrospectionServer(t)
+	auth := &ClientAuth{ClientID: "client1", ClientSecret: "secret1"}
+	resp, err := p.Introspect(context.Background(), auth, "revoked-token", "")
+	if err != nil {
+		t.Fatalf("Introspect() failed: %v", err)
+	}
+	if resp.Active {
+		t.Errorf("expected inactive token")
+	}
+}
+
+func TestIntrospectJWT(t *testing.T) {
+	is, p := newIntrospectionServer(t)
+	auth := &ClientAuth{ClientID: "client1", ClientSecret: "secret1"}
+	resp, err := p.IntrospectJWT(context.Background(), auth, "good-token", "")
+	if err != nil {
+		t.Fatalf("IntrospectJWT() failed: %v", err)
+	}
+	if !resp.Active || resp.Subject != "user1" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	// Responses signed by another key are rejected.
+	is.responseKey = newRSAKey(t)
+	if _, err := p.IntrospectJWT(context.Background(), auth, "good-token", ""); err == nil {
+		t.Errorf("expected error for response signed by an unknown key")
+	}
+}
+
+func TestIntrospectNoEndpoint(t *testing.T) {
+	p := (&ProviderConfig{IssuerURL: "https://example.com"}).NewProvider(context.Background())
+	if _, err := p.Introspect(context.Background(), &ClientAuth{ClientID: "client1"}, "token", ""); err == nil {
+		t.Errorf("expected error for provider without introspection endpoint")
+	}
+}
+
+func TestClientAuthMethod(t *testing.T) {
+	tests := []struct {
+		name      string
+		auth      ClientAuth
+		supported []string
+		want      string
+	}{
+		{
+			name: "secret with unknown methods",
+			auth: ClientAuth{ClientSecret: "secret1"},
+			want: ClientSecretBasic,
+		},
+		{
+			name:      "secret with basic supported",
+			auth:      ClientAuth{ClientSecret: "secret1"},
+			supported: []string{ClientSecretPost, ClientSecretBasic},
+			want:      ClientSecretBasic,
+		},
+		{
+			name:      "secret with only post supported",
+			auth:      ClientAuth{ClientSecret: "secret1"},
+			supported: []string{PrivateKeyJWT, ClientSecretPost},
+			want:      ClientSecretPost,
+		},
+		{
+			name:      "explicit method",
+			auth:      ClientAuth{ClientSecret: "secret1", Method: ClientSecretBasic},
+			supported: []string{ClientSecretPost},
+			want:      ClientSecretBasic,
+		},
+		{
+			name: "private key",
+			auth: ClientAuth{PrivateKey: newECDSAKey(t).priv},
+			want: PrivateKeyJWT,
+		},
+		{
+			name: "public client",
+			want: ClientAuthNone,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.auth.method(test.supported); got != test.want {
+				t.Errorf("method() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
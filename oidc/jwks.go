@@ -6,10 +6,13 @@ import (
 	"crypto/ecdsa"
 	"crypto/ed25519"
 	"crypto/rsa"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -56,15 +59,163 @@ func (s *StaticKeySet) VerifySignature(ctx context.Context, jwt string) ([]byte,
 //
 // The returned KeySet is a long lived verifier that caches keys based on any
 // keys change. Reuse a common remote key set instead of creating new ones as needed.
+//
+// If the context carries a Cache through CacheContext, the key set starts with
+// any keys stored in the cache that are fresh or within the stale grace period,
+// and stores the keys it fetches.
+//
+// If the jwks_uri advertises a lifetime through its Cache-Control or Expires
+// headers, keys are refreshed once that lifetime passes. See RemoteKeySetConfig
+// for tuning this behavior, and for limiting how often tokens with unknown key
+// IDs can trigger requests to the remote.
 func NewRemoteKeySet(ctx context.Context, jwksURL string) *RemoteKeySet {
-	return newRemoteKeySet(ctx, jwksURL, time.Now)
+	r := newRemoteKeySet(ctx, jwksURL, time.Now)
+	r.loadCachedKeys()
+	return r
 }
 
 func newRemoteKeySet(ctx context.Context, jwksURL string, now func() time.Time) *RemoteKeySet {
 	if now == nil {
 		now = time.Now
 	}
-	return &RemoteKeySet{jwksURL: jwksURL, ctx: ctx, now: now}
+	return &RemoteKeySet{
+		jwksURL:          jwksURL,
+		ctx:              ctx,
+		now:              now,
+		cache:            getCache(ctx),
+		staleGracePeriod: defaultStaleGracePeriod,
+	}
+}
+
+// loadCachedKeys initializes the key set from an entry of the persistent cache,
+// if there is one that's fresh or within the stale grace period. It must be
+// called before the key set is used, once its settings are applied.
+func (r *RemoteKeySet) loadCachedKeys() {
+	entry := cacheLoad(r.ctx, r.cache, r.jwksURL, r.now(), r.staleGracePeriod)
+	if entry == nil {
+		return
+	}
+	var keySet jose.JSONWebKeySet
+	if err := json.Unmarshal(entry.Data, &keySet); err != nil {
+		return
+	}
+	r.cachedKeys = keySet.Keys
+	r.expiry = entry.Expiry
+}
+
+// defaultStaleGracePeriod is used when RemoteKeySetConfig doesn't specify a
+// StaleGracePeriod.
+const defaultStaleGracePeriod = time.Hour
+
+// staleRefreshRetryInterval is the minimum amount of time between background
+// refreshes of stale keys after a failed fetch. This keeps verification of
+// tokens signed by known keys from querying an unavailable remote every time.
+const staleRefreshRetryInterval = 30 * time.Second
+
+// RemoteKeySetConfig allows creating remote key sets with non-default caching
+// behavior. It's generally easier to use NewRemoteKeySet directly.
+type RemoteKeySetConfig struct {
+	// StaleGracePeriod is how long cached keys continue to be used after the
+	// lifetime advertised by the jwks_uri's Cache-Control or Expires headers has
+	// passed. During this window keys are refreshed in the background, and the
+	// last good set is served if those refreshes fail. After a failed refresh,
+	// the next one is attempted no sooner than 30 seconds later, or later if
+	// MinRefreshInterval or MaxRefreshBackoff require it. Once the window elapses,
+	// cached keys are no longer trusted and verification requires a successful
+	// fetch.
+	//
+	// If not provided, this defaults to one hour.
+	StaleGracePeriod time.Duration
+
+	// MinRefreshInterval is the minimum amount of time between two fetches of
+	// the jwks_uri. Tokens that require fetching keys during this window, such
+	// as tokens signed with an unknown key ID, fail to verify without making a
+	// request.
+	//
+	// If not provided, fetches aren't rate limited.
+	MinRefreshInterval time.Duration
+
+	// MaxRefreshBackoff enables exponential backoff after failed fetches of the
+	// jwks_uri. The delay before the next fetch starts at MinRefreshInterval, or
+	// one second if that's not set, doubles after every consecutive failure, and
+	// is capped at this value.
+	//
+	// If not provided, failed fetches don't back off.
+	MaxRefreshBackoff time.Duration
+
+	// UnknownKeyIDTTL is how long a key ID that wasn't found in the remote key
+	// set is remembered. Tokens signed with a remembered key ID fail to verify
+	// without fetching the jwks_uri. Key IDs are forgotten early if they show up
+	// in a later fetch.
+	//
+	// If not provided, unknown key IDs aren't remembered.
+	UnknownKeyIDTTL time.Duration
+
+	// RefreshInterval enables fetching keys proactively in the background, so
+	// verification rarely waits on the jwks_uri. Keys are fetched when the key
+	// set is created, then every interval. Failed fetches are retried on the
+	// next tick, and verification still fetches keys on demand if required.
+	//
+	// Background refreshes stop when the context passed to NewRemoteKeySet is
+	// canceled, or when Close is called.
+	//
+	// If not provided, keys are only fetched on demand.
+	RefreshInterval time.Duration
+}
+
+// NewRemoteKeySet initializes a remote key set using the configured caching
+// behavior. See the package level NewRemoteKeySet for details.
+func (c *RemoteKeySetConfig) NewRemoteKeySet(ctx context.Context, jwksURL string) *RemoteKeySet {
+	r := newRemoteKeySet(ctx, jwksURL, time.Now)
+	if c.StaleGracePeriod > 0 {
+		r.staleGracePeriod = c.StaleGracePeriod
+	}
+	r.minRefreshInterval = c.MinRefreshInterval
+	r.maxRefreshBackoff = c.MaxRefreshBackoff
+	r.unknownKeyIDTTL = c.UnknownKeyIDTTL
+	r.loadCachedKeys()
+	if c.RefreshInterval > 0 {
+		r.startRefreshing(c.RefreshInterval)
+	}
+	return r
+}
+
+// startRefreshing fetches keys immediately, then every interval, until the key
+// set is closed.
+func (r *RemoteKeySet) startRefreshing(interval time.Duration) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	r.stopRefreshing = cancel
+	r.refreshDone = make(chan struct{})
+
+	go func() {
+		defer close(r.refreshDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			// Errors are ignored, the next tick tries again.
+			r.keysFromRemote(ctx)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops refreshing keys in the background and waits for the refreshing
+// goroutine to exit. The key set remains usable, fetching keys on demand.
+//
+// Close only needs to be called for key sets created with a RefreshInterval,
+// and is a no-op for others. It's safe to call multiple times.
+func (r *RemoteKeySet) Close() {
+	if r.stopRefreshing == nil {
+		return
+	}
+	r.stopRefreshing()
+	<-r.refreshDone
 }
 
 // RemoteKeySet is a KeySet implementation that validates JSON web tokens against
@@ -73,6 +224,20 @@ type RemoteKeySet struct {
 	jwksURL string
 	ctx     context.Context
 	now     func() time.Time
+	// Persistent cache the keys are loaded from and stored in. May be nil.
+	cache Cache
+
+	// How long stale keys can be used while they're being refreshed.
+	staleGracePeriod time.Duration
+	// Rate limiting of requests to the remote. See RemoteKeySetConfig.
+	minRefreshInterval time.Duration
+	maxRefreshBackoff  time.Duration
+	unknownKeyIDTTL    time.Duration
+
+	// Stops the background refresh goroutine, and is closed once it exits. Only
+	// set if RemoteKeySetConfig.RefreshInterval is.
+	stopRefreshing context.CancelFunc
+	refreshDone    chan struct{}
 
 	// guard all other fields
 	mu sync.RWMutex
@@ -83,8 +248,27 @@ type RemoteKeySet struct {
 
 	// A set of cached keys.
 	cachedKeys []jose.JSONWebKey
+	// When the cached keys become stale, as advertised by the caching headers of
+	// the response that returned them. Zero if no lifetime was advertised.
+	expiry time.Time
+
+	// When the last request to the remote completed, and the number of
+	// consecutive requests that failed.
+	lastRefresh time.Time
+	failures    int
+
+	// Key IDs not found in the remote key set, mapped to when they should be
+	// forgotten.
+	unknownKeyIDs map[string]time.Time
 }
 
+// errRefreshRateLimited is returned when fetching keys is required, but the
+// remote was queried too recently.
+var errRefreshRateLimited = errors.New("oidc: refreshing keys too frequently, try again later")
+
+// maxUnknownKeyIDs bounds the number of unknown key IDs remembered by a key set.
+const maxUnknownKeyIDs = 1024
+
 // inflight is used to wait on some in-flight request from multiple goroutines.
 type inflight struct {
 	doneCh chan struct{}
@@ -117,10 +301,6 @@ func (i *inflight) result() ([]jose.JSONWebKey, error) {
 	return i.keys, i.err
 }
 
-// paresdJWTKey is a context key that allows common setups to avoid parsing the
-// JWT twice. It holds a *jose.JSONWebSignature value.
-var parsedJWTKey contextKey
-
 // VerifySignature validates a payload against a signature from the jwks_uri.
 //
 // Users MUST NOT call this method directly and should use an IDTokenVerifier
@@ -160,6 +340,12 @@ func (r *RemoteKeySet) verify(ctx context.Context, jws *jose.JSONWebSignature) (
 		}
 	}
 
+	// Don't let tokens with a key ID that recently wasn't found trigger
+	// another request.
+	if keyID != "" && r.isUnknownKeyID(keyID) {
+		return nil, fmt.Errorf("oidc: key ID %q not found in key set", keyID)
+	}
+
 	// If the kid doesn't match, check for new keys from the remote. This is the
 	// strategy recommended by the spec.
 	//
@@ -169,89 +355,264 @@ func (r *RemoteKeySet) verify(ctx context.Context, jws *jose.JSONWebSignature) (
 		return nil, fmt.Errorf("fetching keys %w", err)
 	}
 
+	found := false
 	for _, key := range keys {
 		if keyID == "" || key.KeyID == keyID {
+			found = true
 			if payload, err := jws.Verify(&key); err == nil {
 				return payload, nil
 			}
 		}
 	}
+	if keyID != "" && !found {
+		r.addUnknownKeyID(keyID)
+	}
 	return nil, errors.New("failed to verify id token signature")
 }
 
-func (r *RemoteKeySet) keysFromCache() (keys []jose.JSONWebKey) {
+// isUnknownKeyID reports if the key ID was recently not found in the remote key
+// set.
+func (r *RemoteKeySet) isUnknownKeyID(keyID string) bool {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	return r.cachedKeys
+	forgetAt, ok := r.unknownKeyIDs[keyID]
+	return ok && r.now().Before(forgetAt)
+}
+
+// addUnknownKeyID remembers a key ID that wasn't found in the remote key set.
+func (r *RemoteKeySet) addUnknownKeyID(keyID string) {
+	if r.unknownKeyIDTTL <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if r.unknownKeyIDs == nil {
+		r.unknownKeyIDs = make(map[string]time.Time)
+	}
+	if len(r.unknownKeyIDs) >= maxUnknownKeyIDs {
+		for id, forgetAt := range r.unknownKeyIDs {
+			if !now.Before(forgetAt) {
+				delete(r.unknownKeyIDs, id)
+			}
+		}
+		if len(r.unknownKeyIDs) >= maxUnknownKeyIDs {
+			// Requests are still bounded by the refresh interval.
+			return
+		}
+	}
+	r.unknownKeyIDs[keyID] = now.Add(r.unknownKeyIDTTL)
+}
+
+// keysFromCache returns the cached keys that can still be trusted. If the keys
+// are stale, a background refresh is started and they continue to be returned
+// until the grace period elapses.
+func (r *RemoteKeySet) keysFromCache() (keys []jose.JSONWebKey) {
+	r.mu.RLock()
+	keys, expiry := r.cachedKeys, r.expiry
+	lastRefresh, failures := r.lastRefresh, r.failures
+	r.mu.RUnlock()
+
+	if expiry.IsZero() {
+		return keys
+	}
+	now := r.now()
+	if now.Before(expiry) {
+		return keys
+	}
+	if now.Before(expiry.Add(r.staleGracePeriod)) {
+		// Errors are ignored since the stale keys can still be used. A later
+		// call retries the refresh, once the retry interval has passed if the
+		// last one failed.
+		if failures == 0 || !now.Before(lastRefresh.Add(staleRefreshRetryInterval)) {
+			r.refresh()
+		}
+		return keys
+	}
+	return nil
 }
 
 // keysFromRemote syncs the key set from the remote set, records the values in the
 // cache, and returns the key set.
 func (r *RemoteKeySet) keysFromRemote(ctx context.Context) ([]jose.JSONWebKey, error) {
+	inflight, err := r.refresh()
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-inflight.wait():
+		return inflight.result()
+	}
+}
+
+// refresh starts syncing the key set from the remote set if there isn't already
+// a request in flight, and returns the inflight request. It fails if the remote
+// can't be queried yet because of rate limiting.
+func (r *RemoteKeySet) refresh() (*inflight, error) {
 	// Need to lock to inspect the inflight request field.
 	r.mu.Lock()
+	defer r.mu.Unlock()
 	// If there's not a current inflight request, create one.
 	if r.inflight == nil {
+		if r.now().Before(r.nextRefresh()) {
+			return nil, errRefreshRateLimited
+		}
 		r.inflight = newInflight()
 
 		// This goroutine has exclusive ownership over the current inflight
 		// request. It releases the resource by nil'ing the inflight field
 		// once the goroutine is done.
+		inflight := r.inflight
 		go func() {
 			// Sync keys and finish inflight when that's done.
-			keys, err := r.updateKeys()
-
-			r.inflight.done(keys, err)
+			keys, expiry, err := r.updateKeys()
+			completed := r.now()
 
 			// Lock to update the keys and indicate that there is no longer an
-			// inflight request.
+			// inflight request. This happens before signaling waiters so they
+			// observe the updated state.
 			r.mu.Lock()
-			defer r.mu.Unlock()
-
+			r.lastRefresh = completed
 			if err == nil {
 				r.cachedKeys = keys
+				r.expiry = expiry
+				r.failures = 0
+				for _, key := range keys {
+					delete(r.unknownKeyIDs, key.KeyID)
+				}
+			} else {
+				r.failures++
 			}
 
 			// Free inflight so a different request can run.
 			r.inflight = nil
+			r.mu.Unlock()
+
+			inflight.done(keys, err)
 		}()
 	}
-	inflight := r.inflight
-	r.mu.Unlock()
+	return r.inflight, nil
+}
 
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-inflight.wait():
-		return inflight.result()
+// nextRefresh returns the earliest time the remote can be queried again. The
+// caller must hold the lock.
+func (r *RemoteKeySet) nextRefresh() time.Time {
+	if r.lastRefresh.IsZero() {
+		return time.Time{}
+	}
+	if r.failures == 0 || r.maxRefreshBackoff <= 0 {
+		return r.lastRefresh.Add(r.minRefreshInterval)
+	}
+	backoff := r.minRefreshInterval
+	if backoff <= 0 {
+		backoff = time.Second
 	}
+	for i := 1; i < r.failures && backoff < r.maxRefreshBackoff; i++ {
+		backoff *= 2
+	}
+	return r.lastRefresh.Add(min(backoff, r.maxRefreshBackoff))
 }
 
-func (r *RemoteKeySet) updateKeys() ([]jose.JSONWebKey, error) {
+func (r *RemoteKeySet) updateKeys() ([]jose.JSONWebKey, time.Time, error) {
 	req, err := http.NewRequest("GET", r.jwksURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("oidc: can't create request: %v", err)
+		return nil, time.Time{}, fmt.Errorf("oidc: can't create request: %v", err)
 	}
 
 	resp, err := doRequest(r.ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("oidc: get keys failed %w", err)
+		return nil, time.Time{}, fmt.Errorf("oidc: get keys failed %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read response body: %v", err)
+		return nil, time.Time{}, fmt.Errorf("unable to read response body: %v", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("oidc: get keys failed: %s %s", resp.Status, body)
+		return nil, time.Time{}, fmt.Errorf("oidc: get keys failed: %s %s", resp.Status, body)
 	}
 
 	var keySet jose.JSONWebKeySet
 	err = unmarshalResp(resp, body, &keySet)
 	if err != nil {
-		return nil, fmt.Errorf("oidc: failed to decode keys: %v %s", err, body)
+		return nil, time.Time{}, fmt.Errorf("oidc: failed to decode keys: %v %s", err, body)
+	}
+	expiry := cacheExpiry(resp.Header, r.now())
+	cacheStore(r.ctx, r.cache, r.jwksURL, body, expiry)
+	return keySet.Keys, expiry, nil
+}
+
+// cacheExpiry returns when a response becomes stale according to its
+// Cache-Control and Expires headers, following the precedence rules of RFC 9111.
+//
+// It returns the zero time if the response doesn't advertise a positive lifetime.
+// This includes responses that forbid caching through "no-cache" or "max-age=0",
+// which keep the behavior of caching keys until a key ID isn't found.
+func cacheExpiry(h http.Header, now time.Time) time.Time {
+	maxAge, hasMaxAge, noCache := parseCacheControl(h)
+	if noCache {
+		return time.Time{}
+	}
+	var lifetime time.Duration
+	if hasMaxAge {
+		lifetime = maxAge
+	} else if v := h.Get("Expires"); v != "" {
+		expires, err := http.ParseTime(v)
+		if err != nil {
+			return time.Time{}
+		}
+		// Compute the lifetime relative to the server's clock to avoid
+		// depending on clock synchronization.
+		date := now
+		if d, err := http.ParseTime(h.Get("Date")); err == nil {
+			date = d
+		}
+		lifetime = expires.Sub(date)
+	} else {
+		return time.Time{}
+	}
+
+	// Account for time the response spent in intermediate caches.
+	if age, err := strconv.ParseInt(h.Get("Age"), 10, 64); err == nil && age > 0 {
+		lifetime -= time.Duration(min(age, maxDeltaSeconds)) * time.Second
+	}
+	if lifetime <= 0 {
+		return time.Time{}
+	}
+	return now.Add(lifetime)
+}
+
+// maxDeltaSeconds is the largest delta-seconds value honored in caching headers.
+//
+// https://www.rfc-editor.org/rfc/rfc9111#section-1.2.2
+const maxDeltaSeconds = 1 << 31
+
+// parseCacheControl returns the max-age directive of the Cache-Control headers,
+// and whether the response forbids reusing it without revalidation.
+func parseCacheControl(h http.Header) (maxAge time.Duration, hasMaxAge, noCache bool) {
+	for _, v := range h.Values("Cache-Control") {
+		for _, directive := range strings.Split(v, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch strings.ToLower(name) {
+			case "no-cache", "no-store":
+				noCache = true
+			case "max-age":
+				seconds, err := strconv.ParseInt(strings.Trim(value, `"`), 10, 64)
+				if err != nil || seconds < 0 || hasMaxAge {
+					continue
+				}
+				if seconds > maxDeltaSeconds {
+					seconds = maxDeltaSeconds
+				}
+				maxAge, hasMaxAge = time.Duration(seconds)*time.Second, true
+			}
+		}
 	}
-	return keySet.Keys, nil
+	return maxAge, hasMaxAge, noCache
 }
@@ -14,6 +14,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -43,9 +44,18 @@ type signingKey struct {
 
 // sign creates a JWS using the private key from the provided payload.
 func (s *signingKey) sign(t testing.TB, payload []byte) string {
+	return s.signWithType(t, payload, "")
+}
+
+// signWithType creates a JWS like sign, with the provided typ header if not empty.
+func (s *signingKey) signWithType(t testing.TB, payload []byte, typ string) string {
 	privKey := &jose.JSONWebKey{Key: s.priv, Algorithm: string(s.alg), KeyID: s.keyID}
 
-	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: s.alg, Key: privKey}, nil)
+	opts := &jose.SignerOptions{}
+	if typ != "" {
+		opts = opts.WithType(jose.ContentType(typ))
+	}
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: s.alg, Key: privKey}, opts)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -331,3 +341,477 @@ func BenchmarkVerify(b *testing.B) {
 		}
 	}
 }
+
+func TestCacheExpiry(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    time.Time
+	}{
+		{
+			name: "no headers",
+		},
+		{
+			name:    "max-age",
+			headers: map[string]string{"Cache-Control": "public, max-age=600"},
+			want:    now.Add(10 * time.Minute),
+		},
+		{
+			name: "max-age takes precedence over expires",
+			headers: map[string]string{
+				"Cache-Control": "max-age=600",
+				"Expires":       now.Add(time.Hour).Format(http.TimeFormat),
+			},
+			want: now.Add(10 * time.Minute),
+		},
+		{
+			name: "max-age minus age",
+			headers: map[string]string{
+				"Cache-Control": "max-age=600",
+				"Age":           "100",
+			},
+			want: now.Add(500 * time.Second),
+		},
+		{
+			name: "expires relative to date",
+			headers: map[string]string{
+				"Date":    now.Add(-time.Hour).Format(http.TimeFormat),
+				"Expires": now.Format(http.TimeFormat),
+			},
+			want: now.Add(time.Hour),
+		},
+		{
+			name:    "expires without date",
+			headers: map[string]string{"Expires": now.Add(time.Hour).Format(http.TimeFormat)},
+			want:    now.Add(time.Hour),
+		},
+		{
+			name:    "expires in the past",
+			headers: map[string]string{"Expires": now.Add(-time.Hour).Format(http.TimeFormat)},
+		},
+		{
+			name:    "invalid expires",
+			headers: map[string]string{"Expires": "0"},
+		},
+		{
+			name:    "max-age zero",
+			headers: map[string]string{"Cache-Control": "max-age=0"},
+		},
+		{
+			name:    "no-cache",
+			headers: map[string]string{"Cache-Control": "no-cache, max-age=600"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h := http.Header{}
+			for k, v := range test.headers {
+				h.Set(k, v)
+			}
+			got := cacheExpiry(h, now)
+			if !got.Equal(test.want) {
+				t.Errorf("cacheExpiry() got=%v, want=%v", got, test.want)
+			}
+		})
+	}
+}
+
+// waitForRefresh blocks until any inflight request of the key set is done.
+func waitForRefresh(t *testing.T, r *RemoteKeySet) {
+	r.mu.RLock()
+	inflight := r.inflight
+	r.mu.RUnlock()
+	if inflight == nil {
+		return
+	}
+	select {
+	case <-inflight.wait():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for key refresh")
+	}
+}
+
+func TestStaleKeys(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key1 := newRSAKey(t)
+	key2 := newRSAKey(t)
+	key1.keyID = "key1"
+	key2.keyID = "key2"
+
+	payload := []byte("a secret")
+	jws1, err := jose.ParseSigned(key1.sign(t, payload), allAlgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &keyServer{
+		keys: jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{key1.jwk()},
+		},
+		setHeaders: func(h http.Header) {
+			h.Set("Cache-Control", "max-age=60")
+		},
+	}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	now := time.Now()
+	rks := newRemoteKeySet(ctx, s.URL, func() time.Time { return now })
+	rks.staleGracePeriod = time.Minute
+
+	if _, err := rks.verify(ctx, jws1); err != nil {
+		t.Fatalf("failed to verify valid signature: %v", err)
+	}
+
+	// Revoke the first key.
+	server.keys = jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{key2.jwk()},
+	}
+
+	// Keys are still fresh.
+	now = now.Add(30 * time.Second)
+	if _, err := rks.verify(ctx, jws1); err != nil {
+		t.Errorf("failed to verify signature with fresh keys: %v", err)
+	}
+
+	// Keys are stale, but still served while being refreshed.
+	now = now.Add(time.Minute)
+	if _, err := rks.verify(ctx, jws1); err != nil {
+		t.Errorf("failed to verify signature with stale keys: %v", err)
+	}
+	waitForRefresh(t, rks)
+
+	if _, err := rks.verify(ctx, jws1); err == nil {
+		t.Errorf("incorrectly verified signature with revoked key")
+	}
+}
+
+func TestStaleKeysGracePeriod(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := newRSAKey(t)
+	payload := []byte("a secret")
+	jws, err := jose.ParseSigned(key.sign(t, payload), allAlgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &keyServer{
+		keys: jose.JSONWebKeySet{
+			Keys: []jose.JSONWebKey{key.jwk()},
+		},
+		setHeaders: func(h http.Header) {
+			h.Set("Cache-Control", "max-age=60")
+		},
+	}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	now := time.Now()
+	rks := newRemoteKeySet(ctx, s.URL, func() time.Time { return now })
+	rks.staleGracePeriod = time.Minute
+
+	if _, err := rks.verify(ctx, jws); err != nil {
+		t.Fatalf("failed to verify valid signature: %v", err)
+	}
+
+	// Kill server. Stale keys should be served within the grace period.
+	s.Close()
+
+	now = now.Add(90 * time.Second)
+	if _, err := rks.verify(ctx, jws); err != nil {
+		t.Errorf("failed to verify signature within grace period: %v", err)
+	}
+	waitForRefresh(t, rks)
+
+	now = now.Add(time.Minute)
+	if _, err := rks.verify(ctx, jws); err == nil {
+		t.Errorf("incorrectly verified signature after grace period")
+	}
+}
+
+func TestStaleKeysRefreshThrottled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := newRSAKey(t)
+	jws, err := jose.ParseSigned(key.sign(t, []byte("a secret")), allAlgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &countingKeyServer{keyServer: keyServer{
+		keys: jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.jwk()}},
+		setHeaders: func(h http.Header) {
+			h.Set("Cache-Control", "max-age=60")
+		},
+	}}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	now := time.Now()
+	rks := newRemoteKeySet(ctx, s.URL, func() time.Time { return now })
+	if _, err := rks.verify(ctx, jws); err != nil {
+		t.Fatalf("failed to verify valid signature: %v", err)
+	}
+
+	// While the remote is down, stale keys are served without querying it on
+	// every verification.
+	server.fail = true
+	now = now.Add(90 * time.Second)
+	for i := 0; i < 50; i++ {
+		if _, err := rks.verify(ctx, jws); err != nil {
+			t.Fatalf("failed to verify signature with stale keys: %v", err)
+		}
+		waitForRefresh(t, rks)
+	}
+	if got := server.requests.Load(); got != 2 {
+		t.Errorf("expected 2 requests while the remote is failing, got %d", got)
+	}
+
+	// The refresh is retried once the retry interval has passed.
+	now = now.Add(staleRefreshRetryInterval)
+	for i := 0; i < 50; i++ {
+		if _, err := rks.verify(ctx, jws); err != nil {
+			t.Fatalf("failed to verify signature with stale keys: %v", err)
+		}
+		waitForRefresh(t, rks)
+	}
+	if got := server.requests.Load(); got != 3 {
+		t.Errorf("expected 3 requests after the retry interval, got %d", got)
+	}
+}
+
+// countingKeyServer wraps a key server, recording the number of requests and
+// optionally failing them.
+type countingKeyServer struct {
+	keyServer
+	requests atomic.Int64
+	fail     bool
+}
+
+func (c *countingKeyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.requests.Add(1)
+	if c.fail {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	c.keyServer.ServeHTTP(w, r)
+}
+
+func TestRefreshRateLimit(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key1 := newRSAKey(t)
+	key2 := newRSAKey(t)
+	key1.keyID = "key1"
+	key2.keyID = "key2"
+
+	payload := []byte("a secret")
+	jws2, err := jose.ParseSigned(key2.sign(t, payload), allAlgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &countingKeyServer{}
+	server.keys = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key1.jwk()}}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	now := time.Now()
+	rks := newRemoteKeySet(ctx, s.URL, func() time.Time { return now })
+	rks.minRefreshInterval = time.Minute
+
+	if _, err := rks.verify(ctx, jws2); err == nil {
+		t.Fatalf("incorrectly verified signature")
+	}
+
+	// Rotate in the second key. Verification must wait for the refresh interval.
+	server.keys = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key1.jwk(), key2.jwk()}}
+
+	now = now.Add(30 * time.Second)
+	if _, err := rks.verify(ctx, jws2); !errors.Is(err, errRefreshRateLimited) {
+		t.Errorf("expected rate limited error, got %v", err)
+	}
+	if server.requests.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", server.requests.Load())
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := rks.verify(ctx, jws2); err != nil {
+		t.Errorf("failed to verify valid signature: %v", err)
+	}
+	if server.requests.Load() != 2 {
+		t.Errorf("expected 2 requests, got %d", server.requests.Load())
+	}
+}
+
+func TestRefreshBackoff(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		minInterval time.Duration
+		maxBackoff  time.Duration
+		failures    int
+		want        time.Duration
+	}{
+		{"no failures", time.Second, time.Minute, 0, time.Second},
+		{"first failure", time.Second, time.Minute, 1, time.Second},
+		{"third failure", time.Second, time.Minute, 3, 4 * time.Second},
+		{"capped", time.Second, time.Minute, 20, time.Minute},
+		{"default base", 0, time.Minute, 2, 2 * time.Second},
+		{"backoff disabled", time.Second, 0, 5, time.Second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := &RemoteKeySet{
+				minRefreshInterval: test.minInterval,
+				maxRefreshBackoff:  test.maxBackoff,
+				lastRefresh:        now,
+				failures:           test.failures,
+			}
+			if got := r.nextRefresh().Sub(now); got != test.want {
+				t.Errorf("nextRefresh() got=%v, want=%v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUnknownKeyID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key1 := newRSAKey(t)
+	key2 := newRSAKey(t)
+	key1.keyID = "key1"
+	key2.keyID = "key2"
+
+	payload := []byte("a secret")
+	jws2, err := jose.ParseSigned(key2.sign(t, payload), allAlgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &countingKeyServer{}
+	server.keys = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key1.jwk()}}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	now := time.Now()
+	rks := newRemoteKeySet(ctx, s.URL, func() time.Time { return now })
+	rks.unknownKeyIDTTL = time.Minute
+
+	for i := 0; i < 3; i++ {
+		if _, err := rks.verify(ctx, jws2); err == nil {
+			t.Fatalf("incorrectly verified signature")
+		}
+	}
+	if server.requests.Load() != 1 {
+		t.Errorf("expected unknown key ID to be fetched once, got %d requests", server.requests.Load())
+	}
+
+	server.keys = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key1.jwk(), key2.jwk()}}
+	now = now.Add(2 * time.Minute)
+	if _, err := rks.verify(ctx, jws2); err != nil {
+		t.Errorf("failed to verify valid signature: %v", err)
+	}
+	if server.requests.Load() != 2 {
+		t.Errorf("expected 2 requests, got %d", server.requests.Load())
+	}
+}
+
+func TestRefreshBackoffAfterFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := newRSAKey(t)
+	payload := []byte("a secret")
+	jws, err := jose.ParseSigned(key.sign(t, payload), allAlgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &countingKeyServer{fail: true}
+	server.keys = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.jwk()}}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	now := time.Now()
+	rks := (&RemoteKeySetConfig{
+		MinRefreshInterval: time.Second,
+		MaxRefreshBackoff:  time.Minute,
+	}).NewRemoteKeySet(ctx, s.URL)
+	rks.now = func() time.Time { return now }
+
+	// Fail twice, backing off for two seconds.
+	for i := 0; i < 2; i++ {
+		if _, err := rks.verify(ctx, jws); err == nil {
+			t.Fatalf("expected error verifying with failing server")
+		}
+		now = now.Add(time.Second)
+	}
+	server.fail = false
+
+	if _, err := rks.verify(ctx, jws); !errors.Is(err, errRefreshRateLimited) {
+		t.Errorf("expected rate limited error, got %v", err)
+	}
+	now = now.Add(time.Second)
+	if _, err := rks.verify(ctx, jws); err != nil {
+		t.Errorf("failed to verify valid signature: %v", err)
+	}
+	if server.requests.Load() != 3 {
+		t.Errorf("expected 3 requests, got %d", server.requests.Load())
+	}
+}
+
+func TestBackgroundRefresh(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	key := newRSAKey(t)
+	payload := []byte("a secret")
+	jws, err := jose.ParseSigned(key.sign(t, payload), allAlgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &countingKeyServer{}
+	server.keys = jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.jwk()}}
+	s := httptest.NewServer(server)
+	defer s.Close()
+
+	rks := (&RemoteKeySetConfig{RefreshInterval: 10 * time.Millisecond}).NewRemoteKeySet(ctx, s.URL)
+	defer rks.Close()
+
+	// Wait for keys to be refreshed a few times.
+	deadline := time.Now().Add(10 * time.Second)
+	for server.requests.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for background refresh, got %d requests", server.requests.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Keys were prefetched, verification doesn't wait on the remote.
+	if len(rks.keysFromCache()) != 1 {
+		t.Fatalf("expected keys to be prefetched")
+	}
+	if _, err := rks.verify(ctx, jws); err != nil {
+		t.Errorf("failed to verify valid signature: %v", err)
+	}
+
+	rks.Close()
+	rks.Close()
+	waitForRefresh(t, rks)
+
+	got := server.requests.Load()
+	time.Sleep(50 * time.Millisecond)
+	if n := server.requests.Load(); n != got {
+		t.Errorf("expected no requests after Close, got %d", n-got)
+	}
+}
@@ -0,0 +1,106 @@
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// LogoutRequest holds the parameters of an RP-Initiated Logout request.
+//
+// See: https://openid.net/specs/openid-connect-rpinitiated-1_0.html#RPLogout
+type LogoutRequest struct {
+	// IDTokenHint is the raw ID Token previously issued to the user, which the
+	// provider uses to identify the session to end. Recommended.
+	IDTokenHint string
+	// PostLogoutRedirectURI is where the provider redirects the user after logout.
+	// It must be registered with the provider, and requires either IDTokenHint or
+	// ClientID to be set.
+	PostLogoutRedirectURI string
+	// ClientID identifies the client to the provider. Useful if no IDTokenHint is
+	// available, or if the ID Token has been encrypted by the client.
+	ClientID string
+	// LogoutHint is a hint about the user that is logging out, such as an email
+	// address.
+	LogoutHint string
+	// UILocales are the user's preferred languages for the logout pages, as BCP47
+	// language tags in order of preference.
+	UILocales []string
+}
+
+// LogoutURL returns the URL of the provider's end_session_endpoint to redirect the
+// user to, ending their session at the provider.
+//
+// If a PostLogoutRedirectURI is provided, a new state value is generated and
+// included in the request. The state must be stored, for instance in a cookie, and
+// passed to VerifyLogoutCallback when the provider redirects the user back.
+//
+//	logoutURL, state, err := provider.LogoutURL(&oidc.LogoutRequest{
+//		IDTokenHint:           rawIDToken,
+//		PostLogoutRedirectURI: "https://example.com/logged-out",
+//	})
+//	if err != nil {
+//		// handle error
+//	}
+//	// Persist state for the post-logout callback.
+//	http.Redirect(w, r, logoutURL, http.StatusFound)
+//
+// See: https://openid.net/specs/openid-connect-rpinitiated-1_0.html
+func (p *Provider) LogoutURL(req *LogoutRequest) (logoutURL, state string, err error) {
+	md := p.Metadata()
+	if md == nil || md.EndSessionURL == "" {
+		return "", "", errors.New("oidc: provider has no end_session_endpoint")
+	}
+	u, err := url.Parse(md.EndSessionURL)
+	if err != nil {
+		return "", "", fmt.Errorf("oidc: invalid end_session_endpoint: %v", err)
+	}
+
+	// Parameters already part of the endpoint are preserved.
+	v := u.Query()
+	if req.IDTokenHint != "" {
+		v.Set("id_token_hint", req.IDTokenHint)
+	}
+	if req.ClientID != "" {
+		v.Set("client_id", req.ClientID)
+	}
+	if req.LogoutHint != "" {
+		v.Set("logout_hint", req.LogoutHint)
+	}
+	if len(req.UILocales) > 0 {
+		v.Set("ui_locales", strings.Join(req.UILocales, " "))
+	}
+	if req.PostLogoutRedirectURI != "" {
+		if req.IDTokenHint == "" && req.ClientID == "" {
+			return "", "", errors.New("oidc: post logout redirect requires an id token hint or client id")
+		}
+		state, err = randomString()
+		if err != nil {
+			return "", "", err
+		}
+		v.Set("post_logout_redirect_uri", req.PostLogoutRedirectURI)
+		v.Set("state", state)
+	}
+	u.RawQuery = v.Encode()
+	return u.String(), state, nil
+}
+
+// VerifyLogoutCallback validates the parameters of the request to the
+// post_logout_redirect_uri against the state returned by LogoutURL.
+//
+//	http.HandleFunc("/logged-out", func(w http.ResponseWriter, r *http.Request) {
+//		// Load the state stored before redirecting to the provider.
+//		if err := oidc.VerifyLogoutCallback(state, r.URL.Query()); err != nil {
+//			// handle error
+//		}
+//	})
+func VerifyLogoutCallback(state string, params url.Values) error {
+	if state == "" {
+		return errors.New("oidc: no state for logout callback")
+	}
+	if params.Get("state") != state {
+		return errors.New("oidc: state did not match")
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package oidc
+
+import (
+	"context"
+	"net/url"
+	"testing"
+)
+
+func TestLogoutURL(t *testing.T) {
+	tests := []struct {
+		name          string
+		endSessionURL string
+		req           LogoutRequest
+		want          url.Values
+		wantState     bool
+		wantErr       bool
+	}{
+		{
+			name:          "id token hint",
+			endSessionURL: "https://example.com/logout",
+			req:           LogoutRequest{IDTokenHint: "token1"},
+			want:          url.Values{"id_token_hint": {"token1"}},
+		},
+		{
+			name:          "all parameters",
+			endSessionURL: "https://example.com/logout",
+			req: LogoutRequest{
+				IDTokenHint:           "token1",
+				PostLogoutRedirectURI: "https://client.example.com/logged-out",
+				ClientID:              "client1",
+				LogoutHint:            "jdoe@example.com",
+				UILocales:             []string{"fr-CA", "fr", "en"},
+			},
+			want: url.Values{
+				"id_token_hint":            {"token1"},
+				"post_logout_redirect_uri": {"https://client.example.com/logged-out"},
+				"client_id":                {"client1"},
+				"logout_hint":              {"jdoe@example.com"},
+				"ui_locales":               {"fr-CA fr en"},
+			},
+			wantState: true,
+		},
+		{
+			name:          "redirect with client id",
+			endSessionURL: "https://example.com/logout",
+			req: LogoutRequest{
+				PostLogoutRedirectURI: "https://client.example.com/logged-out",
+				ClientID:              "client1",
+			},
+			want: url.Values{
+				"post_logout_redirect_uri": {"https://client.example.com/logged-out"},
+				"client_id":                {"client1"},
+			},
+			wantState: true,
+		},
+		{
+			name:          "existing query parameters",
+			endSessionURL: "https://example.com/logout?tenant=a",
+			req:           LogoutRequest{ClientID: "client1"},
+			want:          url.Values{"tenant": {"a"}, "client_id": {"client1"}},
+		},
+		{
+			name:          "redirect without hint or client id",
+			endSessionURL: "https://example.com/logout",
+			req:           LogoutRequest{PostLogoutRedirectURI: "https://client.example.com/logged-out"},
+			wantErr:       true,
+		},
+		{
+			name:    "no end session endpoint",
+			req:     LogoutRequest{IDTokenHint: "token1"},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := (&ProviderConfig{
+				IssuerURL:     "https://example.com",
+				EndSessionURL: test.endSessionURL,
+			}).NewProvider(context.Background())
+			logoutURL, state, err := p.LogoutURL(&test.req)
+			if err != nil {
+				if !test.wantErr {
+					t.Fatalf("LogoutURL() failed: %v", err)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatalf("LogoutURL(): expected error")
+			}
+
+			u, err := url.Parse(logoutURL)
+			if err != nil {
+				t.Fatalf("parse logout url: %v", err)
+			}
+			if got := u.Scheme + "://" + u.Host + u.Path; got != "https://example.com/logout" {
+				t.Errorf("unexpected endpoint %q", got)
+			}
+			got := u.Query()
+			if test.wantState {
+				if state == "" || got.Get("state") != state {
+					t.Errorf("expected state %q in logout url, got %q", state, got.Get("state"))
+				}
+				got.Del("state")
+			} else if state != "" {
+				t.Errorf("expected no state without a post logout redirect, got %q", state)
+			}
+			if got.Encode() != test.want.Encode() {
+				t.Errorf("unexpected logout parameters, got=%s, want=%s", got.Encode(), test.want.Encode())
+			}
+		})
+	}
+}
+
+func TestVerifyLogoutCallback(t *testing.T) {
+	p := (&ProviderConfig{
+		IssuerURL:     "https://example.com",
+		EndSessionURL: "https://example.com/logout",
+	}).NewProvider(context.Background())
+	_, state, err := p.LogoutURL(&LogoutRequest{
+		ClientID:              "client1",
+		PostLogoutRedirectURI: "https://client.example.com/logged-out",
+	})
+	if err != nil {
+		t.Fatalf("LogoutURL() failed: %v", err)
+	}
+
+	if err := VerifyLogoutCallback(state, url.Values{"state": {state}}); err != nil {
+		t.Errorf("VerifyLogoutCallback() failed: %v", err)
+	}
+	if err := VerifyLogoutCallback(state, url.Values{"state": {"other"}}); err == nil {
+		t.Errorf("expected error for mismatched state")
+	}
+	if err := VerifyLogoutCallback(state, url.Values{}); err == nil {
+		t.Errorf("expected error for missing state")
+	}
+	if err := VerifyLogoutCallback("", url.Values{}); err == nil {
+		t.Errorf("expected error for empty stored state")
+	}
+}
@@ -0,0 +1,208 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ProviderMetadata holds the values advertised by a provider's discovery
+// document, as defined by OpenID Connect Discovery 1.0 and OAuth 2.0
+// Authorization Server Metadata (RFC 8414).
+//
+// Fields the provider omits are set to the defaults defined by those
+// specifications. Extensions not covered by this type can be accessed through
+// Provider.Claims.
+//
+// See: https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+// and https://www.rfc-editor.org/rfc/rfc8414#section-2
+type ProviderMetadata struct {
+	// Issuer is the identifier of the provider, which must match the "iss"
+	// claim of the tokens it issues.
+	Issuer string `json:"issuer"`
+
+	// Endpoints of the provider.
+	AuthURL               string `json:"authorization_endpoint,omitempty"`
+	TokenURL              string `json:"token_endpoint,omitempty"`
+	DeviceAuthURL         string `json:"device_authorization_endpoint,omitempty"`
+	UserInfoURL           string `json:"userinfo_endpoint,omitempty"`
+	JWKSURL               string `json:"jwks_uri,omitempty"`
+	RegistrationURL       string `json:"registration_endpoint,omitempty"`
+	RevocationURL         string `json:"revocation_endpoint,omitempty"`
+	IntrospectionURL      string `json:"introspection_endpoint,omitempty"`
+	EndSessionURL         string `json:"end_session_endpoint,omitempty"`
+	CheckSessionIFrameURL string `json:"check_session_iframe,omitempty"`
+
+	// Values of the authorization request supported by the provider.
+	ScopesSupported               []string `json:"scopes_supported,omitempty"`
+	ResponseTypesSupported        []string `json:"response_types_supported,omitempty"`
+	ResponseModesSupported        []string `json:"response_modes_supported,omitempty"`
+	GrantTypesSupported           []string `json:"grant_types_supported,omitempty"`
+	ACRValuesSupported            []string `json:"acr_values_supported,omitempty"`
+	SubjectTypesSupported         []string `json:"subject_types_supported,omitempty"`
+	DisplayValuesSupported        []string `json:"display_values_supported,omitempty"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported,omitempty"`
+
+	// Algorithms used to sign and encrypt ID tokens, userinfo responses, and
+	// request objects.
+	IDTokenSigningAlgValuesSupported           []string `json:"id_token_signing_alg_values_supported,omitempty"`
+	IDTokenEncryptionAlgValuesSupported        []string `json:"id_token_encryption_alg_values_supported,omitempty"`
+	IDTokenEncryptionEncValuesSupported        []string `json:"id_token_encryption_enc_values_supported,omitempty"`
+	UserInfoSigningAlgValuesSupported          []string `json:"userinfo_signing_alg_values_supported,omitempty"`
+	UserInfoEncryptionAlgValuesSupported       []string `json:"userinfo_encryption_alg_values_supported,omitempty"`
+	UserInfoEncryptionEncValuesSupported       []string `json:"userinfo_encryption_enc_values_supported,omitempty"`
+	RequestObjectSigningAlgValuesSupported     []string `json:"request_object_signing_alg_values_supported,omitempty"`
+	RequestObjectEncryptionAlgValuesSupported  []string `json:"request_object_encryption_alg_values_supported,omitempty"`
+	RequestObjectEncryptionEncValuesSupported  []string `json:"request_object_encryption_enc_values_supported,omitempty"`
+	TokenEndpointAuthMethodsSupported          []string `json:"token_endpoint_auth_methods_supported,omitempty"`
+	TokenEndpointAuthSigningAlgValuesSupported []string `json:"token_endpoint_auth_signing_alg_values_supported,omitempty"`
+
+	// Client authentication supported by the revocation and introspection
+	// endpoints.
+	RevocationEndpointAuthMethodsSupported             []string `json:"revocation_endpoint_auth_methods_supported,omitempty"`
+	RevocationEndpointAuthSigningAlgValuesSupported    []string `json:"revocation_endpoint_auth_signing_alg_values_supported,omitempty"`
+	IntrospectionEndpointAuthMethodsSupported          []string `json:"introspection_endpoint_auth_methods_supported,omitempty"`
+	IntrospectionEndpointAuthSigningAlgValuesSupported []string `json:"introspection_endpoint_auth_signing_alg_values_supported,omitempty"`
+
+	// Claims supported by the provider.
+	ClaimTypesSupported    []string `json:"claim_types_supported,omitempty"`
+	ClaimsSupported        []string `json:"claims_supported,omitempty"`
+	ClaimsLocalesSupported []string `json:"claims_locales_supported,omitempty"`
+	UILocalesSupported     []string `json:"ui_locales_supported,omitempty"`
+
+	// Request parameters supported by the provider.
+	ClaimsParameterSupported      bool `json:"claims_parameter_supported"`
+	RequestParameterSupported     bool `json:"request_parameter_supported"`
+	RequestURIParameterSupported  bool `json:"request_uri_parameter_supported"`
+	RequireRequestURIRegistration bool `json:"require_request_uri_registration"`
+
+	// Logout mechanisms supported by the provider.
+	//
+	// See: https://openid.net/specs/openid-connect-backchannel-1_0.html#BCSupport
+	// and https://openid.net/specs/openid-connect-frontchannel-1_0.html#OPLogout
+	BackChannelLogoutSupported         bool `json:"backchannel_logout_supported"`
+	BackChannelLogoutSessionSupported  bool `json:"backchannel_logout_session_supported"`
+	FrontChannelLogoutSupported        bool `json:"frontchannel_logout_supported"`
+	FrontChannelLogoutSessionSupported bool `json:"frontchannel_logout_session_supported"`
+
+	// Human readable documentation of the provider.
+	ServiceDocumentationURL string `json:"service_documentation,omitempty"`
+	PolicyURL               string `json:"op_policy_uri,omitempty"`
+	TermsOfServiceURL       string `json:"op_tos_uri,omitempty"`
+}
+
+// UnmarshalJSON decodes a discovery document, applying the defaults defined by
+// the specifications to omitted fields.
+func (m *ProviderMetadata) UnmarshalJSON(b []byte) error {
+	type metadata ProviderMetadata
+	md := metadata{
+		RequestURIParameterSupported: true,
+	}
+	if err := json.Unmarshal(b, &md); err != nil {
+		return err
+	}
+
+	defaults := []struct {
+		field *[]string
+		value []string
+	}{
+		{&md.ResponseModesSupported, []string{"query", "fragment"}},
+		{&md.GrantTypesSupported, []string{"authorization_code", "implicit"}},
+		{&md.TokenEndpointAuthMethodsSupported, []string{"client_secret_basic"}},
+		{&md.RevocationEndpointAuthMethodsSupported, []string{"client_secret_basic"}},
+		{&md.IntrospectionEndpointAuthMethodsSupported, []string{"client_secret_basic"}},
+		{&md.ClaimTypesSupported, []string{"normal"}},
+	}
+	for _, d := range defaults {
+		if *d.field == nil {
+			*d.field = d.value
+		}
+	}
+	*m = ProviderMetadata(md)
+	return nil
+}
+
+// parseProviderMetadata decodes a discovery document on a best effort basis.
+// Members whose value doesn't match the type of the corresponding field are
+// ignored, so a provider publishing malformed optional metadata can still be
+// used. Ignored members keep their defaults.
+func parseProviderMetadata(b []byte) *ProviderMetadata {
+	var m ProviderMetadata
+	if err := json.Unmarshal(b, &m); err == nil {
+		return &m
+	}
+
+	var members map[string]json.RawMessage
+	json.Unmarshal(b, &members)
+	valid := make(map[string]json.RawMessage, len(members))
+	for name, value := range members {
+		member, err := json.Marshal(map[string]json.RawMessage{name: value})
+		if err != nil {
+			continue
+		}
+		var scratch ProviderMetadata
+		if err := json.Unmarshal(member, &scratch); err == nil {
+			valid[name] = value
+		}
+	}
+	// Only holds members which decode on their own, so this can't fail.
+	filtered, _ := json.Marshal(valid)
+	json.Unmarshal(filtered, &m)
+	return &m
+}
+
+// Validate checks that the metadata holds the fields OpenID Connect Discovery
+// requires. NewProvider doesn't enforce these requirements since many providers
+// omit some of them, but callers may use this method to be strict.
+func (m *ProviderMetadata) Validate() error {
+	var missing []string
+	required := []struct {
+		name    string
+		missing bool
+	}{
+		{"issuer", m.Issuer == ""},
+		{"authorization_endpoint", m.AuthURL == ""},
+		// The token endpoint is only optional for providers which only support
+		// the implicit flow.
+		{"token_endpoint", m.TokenURL == "" && !m.onlyImplicit()},
+		{"jwks_uri", m.JWKSURL == ""},
+		{"response_types_supported", len(m.ResponseTypesSupported) == 0},
+		{"subject_types_supported", len(m.SubjectTypesSupported) == 0},
+		{"id_token_signing_alg_values_supported", len(m.IDTokenSigningAlgValuesSupported) == 0},
+	}
+	for _, r := range required {
+		if r.missing {
+			missing = append(missing, r.name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("oidc: provider metadata missing required fields: %s", strings.Join(missing, ", "))
+	}
+
+	u, err := url.Parse(m.Issuer)
+	if err != nil {
+		return fmt.Errorf("oidc: invalid issuer %q: %v", m.Issuer, err)
+	}
+	if u.Scheme != "https" || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("oidc: invalid issuer %q: must be an https URL without query or fragment", m.Issuer)
+	}
+	if !contains(m.IDTokenSigningAlgValuesSupported, RS256) {
+		return fmt.Errorf("oidc: provider metadata id_token_signing_alg_values_supported must include %s", RS256)
+	}
+	return nil
+}
+
+// onlyImplicit reports if all supported response types belong to the implicit
+// flow, which doesn't use the token endpoint.
+func (m *ProviderMetadata) onlyImplicit() bool {
+	if len(m.ResponseTypesSupported) == 0 {
+		return false
+	}
+	for _, rt := range m.ResponseTypesSupported {
+		if contains(strings.Fields(rt), "code") {
+			return false
+		}
+	}
+	return true
+}
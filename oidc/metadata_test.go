@@ -0,0 +1,217 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProviderMetadataDefaults(t *testing.T) {
+	var m ProviderMetadata
+	if err := json.Unmarshal([]byte(`{"issuer":"https://example.com"}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	want := ProviderMetadata{
+		Issuer:                                    "https://example.com",
+		ResponseModesSupported:                    []string{"query", "fragment"},
+		GrantTypesSupported:                       []string{"authorization_code", "implicit"},
+		TokenEndpointAuthMethodsSupported:         []string{"client_secret_basic"},
+		RevocationEndpointAuthMethodsSupported:    []string{"client_secret_basic"},
+		IntrospectionEndpointAuthMethodsSupported: []string{"client_secret_basic"},
+		ClaimTypesSupported:                       []string{"normal"},
+		RequestURIParameterSupported:              true,
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("unexpected metadata\ngot=  %#v\nwant= %#v", m, want)
+	}
+
+	if err := json.Unmarshal([]byte(`{"grant_types_supported":["refresh_token"],"request_uri_parameter_supported":false}`), &m); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(m.GrantTypesSupported, []string{"refresh_token"}) {
+		t.Errorf("expected advertised grant types to be used, got %q", m.GrantTypesSupported)
+	}
+	if m.RequestURIParameterSupported {
+		t.Errorf("expected advertised request_uri_parameter_supported to be used")
+	}
+}
+
+func TestProviderMetadataMalformed(t *testing.T) {
+	var issuer string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{
+			"issuer": %[1]q,
+			"token_endpoint": "%[1]s/token",
+			"claims_parameter_supported": "true",
+			"grant_types_supported": "authorization_code",
+			"scopes_supported": ["openid", "email"],
+			"request_uri_parameter_supported": false
+		}`, issuer)
+	}))
+	defer s.Close()
+	issuer = s.URL
+
+	// Optional fields with unexpected types don't fail discovery.
+	p, err := NewProvider(context.Background(), issuer)
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	if got := p.Endpoint().TokenURL; got != issuer+"/token" {
+		t.Errorf("unexpected token endpoint %q", got)
+	}
+
+	m := p.Metadata()
+	if m.Issuer != issuer || m.TokenURL != issuer+"/token" {
+		t.Errorf("unexpected metadata %#v", m)
+	}
+	if m.ClaimsParameterSupported {
+		t.Errorf("expected malformed claims_parameter_supported to be ignored")
+	}
+	if !reflect.DeepEqual(m.GrantTypesSupported, []string{"authorization_code", "implicit"}) {
+		t.Errorf("expected malformed grant_types_supported to use the default, got %q", m.GrantTypesSupported)
+	}
+	if !reflect.DeepEqual(m.ScopesSupported, []string{"openid", "email"}) {
+		t.Errorf("unexpected scopes_supported %q", m.ScopesSupported)
+	}
+	if m.RequestURIParameterSupported {
+		t.Errorf("expected advertised request_uri_parameter_supported to be used")
+	}
+
+	var claims struct {
+		ClaimsParameterSupported string `json:"claims_parameter_supported"`
+	}
+	if err := p.Claims(&claims); err != nil || claims.ClaimsParameterSupported != "true" {
+		t.Errorf("expected raw claims to be available, got %q, %v", claims.ClaimsParameterSupported, err)
+	}
+}
+
+func TestProviderMetadataValidate(t *testing.T) {
+	valid := func() *ProviderMetadata {
+		return &ProviderMetadata{
+			Issuer:                           "https://example.com",
+			AuthURL:                          "https://example.com/auth",
+			TokenURL:                         "https://example.com/token",
+			JWKSURL:                          "https://example.com/keys",
+			ResponseTypesSupported:           []string{"code"},
+			SubjectTypesSupported:            []string{"public"},
+			IDTokenSigningAlgValuesSupported: []string{RS256},
+		}
+	}
+	tests := []struct {
+		name    string
+		modify  func(m *ProviderMetadata)
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			modify: func(m *ProviderMetadata) {},
+		},
+		{
+			name: "missing fields",
+			modify: func(m *ProviderMetadata) {
+				m.JWKSURL = ""
+				m.SubjectTypesSupported = nil
+			},
+			wantErr: "jwks_uri, subject_types_supported",
+		},
+		{
+			name: "implicit only without token endpoint",
+			modify: func(m *ProviderMetadata) {
+				m.TokenURL = ""
+				m.ResponseTypesSupported = []string{"id_token", "id_token token"}
+			},
+		},
+		{
+			name: "code flow without token endpoint",
+			modify: func(m *ProviderMetadata) {
+				m.TokenURL = ""
+				m.ResponseTypesSupported = []string{"id_token", "code id_token"}
+			},
+			wantErr: "token_endpoint",
+		},
+		{
+			name:    "http issuer",
+			modify:  func(m *ProviderMetadata) { m.Issuer = "http://example.com" },
+			wantErr: "must be an https URL",
+		},
+		{
+			name:    "issuer with query",
+			modify:  func(m *ProviderMetadata) { m.Issuer = "https://example.com?tenant=1" },
+			wantErr: "must be an https URL",
+		},
+		{
+			name:    "missing RS256",
+			modify:  func(m *ProviderMetadata) { m.IDTokenSigningAlgValuesSupported = []string{ES256} },
+			wantErr: "must include RS256",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := valid()
+			test.modify(m)
+			err := m.Validate()
+			if test.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("expected error containing %q, got %v", test.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestProviderMetadata(t *testing.T) {
+	var issuer string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{
+			"issuer": %[1]q,
+			"authorization_endpoint": "%[1]s/auth",
+			"end_session_endpoint": "%[1]s/logout",
+			"revocation_endpoint": "%[1]s/revoke",
+			"backchannel_logout_supported": true,
+			"frontchannel_logout_supported": true,
+			"frontchannel_logout_session_supported": true,
+			"scopes_supported": ["openid", "email"],
+			"code_challenge_methods_supported": ["S256"]
+		}`, issuer)
+	}))
+	defer s.Close()
+	issuer = s.URL
+
+	p, err := NewProvider(context.Background(), issuer)
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	m := p.Metadata()
+	if m.EndSessionURL != issuer+"/logout" {
+		t.Errorf("unexpected end_session_endpoint %q", m.EndSessionURL)
+	}
+	if m.RevocationURL != issuer+"/revoke" {
+		t.Errorf("unexpected revocation_endpoint %q", m.RevocationURL)
+	}
+	if !m.BackChannelLogoutSupported || m.BackChannelLogoutSessionSupported {
+		t.Errorf("unexpected back-channel logout support %t, session %t", m.BackChannelLogoutSupported, m.BackChannelLogoutSessionSupported)
+	}
+	if !m.FrontChannelLogoutSupported || !m.FrontChannelLogoutSessionSupported {
+		t.Errorf("unexpected front-channel logout support %t, session %t", m.FrontChannelLogoutSupported, m.FrontChannelLogoutSessionSupported)
+	}
+	if !reflect.DeepEqual(m.ScopesSupported, []string{"openid", "email"}) {
+		t.Errorf("unexpected scopes_supported %q", m.ScopesSupported)
+	}
+	if !reflect.DeepEqual(m.CodeChallengeMethodsSupported, []string{"S256"}) {
+		t.Errorf("unexpected code_challenge_methods_supported %q", m.CodeChallengeMethodsSupported)
+	}
+
+	p = (&ProviderConfig{IssuerURL: issuer, TokenURL: issuer + "/token"}).NewProvider(context.Background())
+	if m := p.Metadata(); m.Issuer != issuer || m.TokenURL != issuer+"/token" {
+		t.Errorf("unexpected metadata for configured provider %#v", m)
+	}
+}
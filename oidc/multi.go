@@ -0,0 +1,157 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MultiIssuerVerifier verifies ID Tokens issued by any of a fixed set of trusted
+// providers. Each token is routed to the verifier for the provider named by its
+// iss claim, and tokens from issuers not in the set are rejected.
+//
+// Providers are discovered lazily the first time a token from their issuer is
+// verified, so an unavailable provider doesn't prevent verifying tokens from the
+// others. Failed discoveries are retried no more than every 30 seconds.
+//
+//	verifier := oidc.NewMultiIssuerVerifier(ctx, map[string]*oidc.Config{
+//		"https://corp.example.com":    {ClientID: corpClientID},
+//		"https://partner.example.com": {ClientID: partnerClientID},
+//	})
+//
+//	token, err := verifier.Verify(ctx, rawIDToken)
+type MultiIssuerVerifier struct {
+	// ctx is used for discovery and key set requests.
+	ctx context.Context
+	// Read only after construction.
+	issuers map[string]*issuerVerifier
+
+	// Time function used to retry failed discoveries. Defaults to time.Now
+	now func() time.Time
+}
+
+// discoveryRetryInterval is how long a failed discovery is remembered before a
+// token from the same issuer triggers another one. Tokens don't need a valid
+// signature to trigger discovery, so this bounds the requests made to an
+// unavailable provider.
+const discoveryRetryInterval = 30 * time.Second
+
+type issuerVerifier struct {
+	config *Config
+
+	// Guards the fields below.
+	mu       sync.Mutex
+	verifier *IDTokenVerifier
+	// Closed once the in-flight discovery is done, nil if there isn't one.
+	// Concurrent tokens from the same issuer wait on the same discovery.
+	discovering chan struct{}
+	// Error of the last failed discovery, and when it failed.
+	err      error
+	failedAt time.Time
+}
+
+// NewMultiIssuerVerifier returns a verifier which accepts tokens from the issuers in
+// configs, verifying each with the config for its issuer.
+//
+// The context is used for provider discovery and for requests to the providers' key
+// sets, similar to calling NewProvider and (*Provider).Verifier with that context.
+// The context passed to Verify only bounds how long the call waits for discovery.
+func NewMultiIssuerVerifier(ctx context.Context, configs map[string]*Config) *MultiIssuerVerifier {
+	issuers := make(map[string]*issuerVerifier, len(configs))
+	for issuer, config := range configs {
+		if config == nil {
+			config = &Config{}
+		}
+		issuers[issuer] = &issuerVerifier{config: config}
+	}
+	return &MultiIssuerVerifier{ctx: ctx, issuers: issuers, now: time.Now}
+}
+
+// Verify parses the unverified iss claim of a raw ID Token, discovers the matching
+// provider if this hasn't been done yet, and verifies the token with the provider's
+// verifier. Tokens from issuers which weren't passed to NewMultiIssuerVerifier are
+// rejected before any network requests are made.
+func (m *MultiIssuerVerifier) Verify(ctx context.Context, rawIDToken string) (*IDToken, error) {
+	return m.VerifyWithOptions(ctx, rawIDToken)
+}
+
+// VerifyWithOptions is like Verify, but performs additional checks requested by
+// the options, such as validating the nonce with ExpectNonce.
+func (m *MultiIssuerVerifier) VerifyWithOptions(ctx context.Context, rawIDToken string, opts ...VerifyOption) (*IDToken, error) {
+	payload, err := parseJWT(rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: malformed jwt: %v", err)
+	}
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("oidc: failed to unmarshal claims: %v", err)
+	}
+
+	issuer := claims.Issuer
+	if issuer == issuerGoogleAccountsNoScheme {
+		// See the Google special case in (*IDTokenVerifier).Verify.
+		issuer = issuerGoogleAccounts
+	}
+	iv, ok := m.issuers[issuer]
+	if !ok {
+		return nil, fmt.Errorf("oidc: id token issued by untrusted issuer %q", claims.Issuer)
+	}
+	v, err := m.getVerifier(ctx, iv, issuer)
+	if err != nil {
+		return nil, err
+	}
+	return v.VerifyWithOptions(ctx, rawIDToken, opts...)
+}
+
+// getVerifier returns the verifier of an issuer, discovering the provider if
+// required. Discovery uses the MultiIssuerVerifier's context, since its result
+// is shared, while ctx bounds how long the caller waits for it.
+func (m *MultiIssuerVerifier) getVerifier(ctx context.Context, iv *issuerVerifier, issuer string) (*IDTokenVerifier, error) {
+	iv.mu.Lock()
+	if iv.verifier != nil {
+		defer iv.mu.Unlock()
+		return iv.verifier, nil
+	}
+	if iv.err != nil && m.now().Before(iv.failedAt.Add(discoveryRetryInterval)) {
+		defer iv.mu.Unlock()
+		return nil, iv.err
+	}
+	if iv.discovering == nil {
+		done := make(chan struct{})
+		iv.discovering = done
+		go func() {
+			p, err := NewProvider(m.ctx, issuer)
+
+			iv.mu.Lock()
+			if err != nil {
+				iv.err = fmt.Errorf("oidc: discovering issuer %q: %v", issuer, err)
+				iv.failedAt = m.now()
+			} else {
+				iv.verifier = p.Verifier(iv.config)
+				iv.err = nil
+			}
+			iv.discovering = nil
+			iv.mu.Unlock()
+			close(done)
+		}()
+	}
+	done := iv.discovering
+	iv.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-done:
+	}
+
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	if iv.verifier != nil {
+		return iv.verifier, nil
+	}
+	return nil, iv.err
+}
@@ -0,0 +1,205 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+)
+
+// countingIssuer serves discovery for a testIssuer and counts discovery requests.
+type countingIssuer struct {
+	testIssuer
+	discoveries atomic.Int64
+}
+
+func (c *countingIssuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/.well-known/openid-configuration" {
+		c.discoveries.Add(1)
+	}
+	c.testIssuer.ServeHTTP(w, r)
+}
+
+func newCountingIssuer(t *testing.T, key *signingKey) *countingIssuer {
+	c := &countingIssuer{testIssuer: testIssuer{
+		algs: []string{RS256},
+		jwks: &jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key.jwk()}},
+	}}
+	s := httptest.NewServer(c)
+	t.Cleanup(s.Close)
+	c.baseURL = s.URL
+	return c
+}
+
+func TestMultiIssuerVerifier(t *testing.T) {
+	key1 := newRSAKey(t)
+	key2 := newRSAKey(t)
+	issuer1 := newCountingIssuer(t, key1)
+	issuer2 := newCountingIssuer(t, key2)
+
+	newToken := func(key *signingKey, issuer, aud string) string {
+		payload, err := json.Marshal(map[string]interface{}{
+			"iss": issuer,
+			"sub": "test-user",
+			"aud": aud,
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key.sign(t, payload)
+	}
+
+	ctx := context.Background()
+	v := NewMultiIssuerVerifier(ctx, map[string]*Config{
+		issuer1.baseURL: {ClientID: "client1"},
+		issuer2.baseURL: {ClientID: "client2"},
+	})
+
+	if _, err := v.Verify(ctx, newToken(key1, issuer1.baseURL, "client1")); err != nil {
+		t.Fatalf("Verify() for first issuer failed: %v", err)
+	}
+	if n := issuer2.discoveries.Load(); n != 0 {
+		t.Errorf("expected second issuer not to be discovered, got %d requests", n)
+	}
+	if _, err := v.Verify(ctx, newToken(key2, issuer2.baseURL, "client2")); err != nil {
+		t.Fatalf("Verify() for second issuer failed: %v", err)
+	}
+	if _, err := v.Verify(ctx, newToken(key1, issuer1.baseURL, "client1")); err != nil {
+		t.Fatalf("Verify() for first issuer failed: %v", err)
+	}
+	if n := issuer1.discoveries.Load(); n != 1 {
+		t.Errorf("expected one discovery request for first issuer, got %d", n)
+	}
+
+	// Each issuer uses its own config and keys.
+	if _, err := v.Verify(ctx, newToken(key1, issuer1.baseURL, "client2")); err == nil {
+		t.Errorf("expected error for audience of a different issuer")
+	}
+	if _, err := v.Verify(ctx, newToken(key1, issuer2.baseURL, "client2")); err == nil {
+		t.Errorf("expected error for token signed by a different issuer's key")
+	}
+
+	untrusted := newCountingIssuer(t, key1)
+	if _, err := v.Verify(ctx, newToken(key1, untrusted.baseURL, "client1")); err == nil {
+		t.Errorf("expected error for untrusted issuer")
+	}
+	if n := untrusted.discoveries.Load(); n != 0 {
+		t.Errorf("expected untrusted issuer not to be discovered, got %d requests", n)
+	}
+}
+
+func TestMultiIssuerVerifierDiscoveryFailure(t *testing.T) {
+	key := newRSAKey(t)
+	issuer := newCountingIssuer(t, key)
+
+	var fail atomic.Bool
+	fail.Store(true)
+	var requests atomic.Int64
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if fail.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		issuer.ServeHTTP(w, r)
+	}))
+	defer s.Close()
+	issuer.baseURL = s.URL
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"iss": s.URL,
+		"aud": "client1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawIDToken := key.sign(t, payload)
+
+	ctx := context.Background()
+	now := time.Now()
+	v := NewMultiIssuerVerifier(ctx, map[string]*Config{s.URL: {ClientID: "client1"}})
+	v.now = func() time.Time { return now }
+
+	if _, err := v.Verify(ctx, rawIDToken); err == nil {
+		t.Fatalf("expected error while provider is unavailable")
+	}
+	// Failures are remembered, so tokens don't trigger a discovery each.
+	discoveryRequests := requests.Load()
+	for i := 0; i < 10; i++ {
+		if _, err := v.Verify(ctx, rawIDToken); err == nil {
+			t.Fatalf("expected error while provider is unavailable")
+		}
+	}
+	if n := requests.Load(); n != discoveryRequests {
+		t.Errorf("expected no requests while a failure is remembered, got %d", n-discoveryRequests)
+	}
+
+	fail.Store(false)
+	if _, err := v.Verify(ctx, rawIDToken); err == nil {
+		t.Errorf("expected remembered error before the retry interval passed")
+	}
+	now = now.Add(discoveryRetryInterval)
+	if _, err := v.Verify(ctx, rawIDToken); err != nil {
+		t.Fatalf("Verify() failed after provider became available: %v", err)
+	}
+}
+
+func TestMultiIssuerVerifierDiscoveryInFlight(t *testing.T) {
+	key := newRSAKey(t)
+	issuer := newCountingIssuer(t, key)
+
+	release := make(chan struct{})
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/openid-configuration" {
+			<-release
+		}
+		issuer.ServeHTTP(w, r)
+	}))
+	defer s.Close()
+	issuer.baseURL = s.URL
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"iss": s.URL,
+		"aud": "client1",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawIDToken := key.sign(t, payload)
+
+	v := NewMultiIssuerVerifier(context.Background(), map[string]*Config{s.URL: {ClientID: "client1"}})
+
+	// Callers waiting on a slow discovery can give up.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := v.Verify(ctx, rawIDToken); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded while discovery is in flight, got %v", err)
+	}
+
+	// Concurrent callers share the in-flight discovery.
+	errs := make(chan error, 10)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			_, err := v.Verify(context.Background(), rawIDToken)
+			errs <- err
+		}()
+	}
+	close(release)
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Verify() failed: %v", err)
+		}
+	}
+	if n := issuer.discoveries.Load(); n != 1 {
+		t.Errorf("expected one discovery request, got %d", n)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"io"
 	"mime"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
 	"time"
@@ -36,13 +37,24 @@ const (
 )
 
 var (
-	errNoAtHash      = errors.New("id token did not have an access token hash")
-	errInvalidAtHash = errors.New("access token hash does not match value in ID token")
+	errNoAtHash         = errors.New("id token did not have an access token hash")
+	errInvalidAtHash    = errors.New("access token hash does not match value in ID token")
+	errNoCodeHash       = errors.New("id token did not have an authorization code hash")
+	errInvalidCodeHash  = errors.New("authorization code hash does not match value in ID token")
+	errNoStateHash      = errors.New("id token did not have a state hash")
+	errInvalidStateHash = errors.New("state hash does not match value in ID token")
 )
 
 type contextKey int
 
-var issuerURLKey contextKey
+const (
+	issuerURLKey contextKey = iota
+	// paresdJWTKey is a context key that allows common setups to avoid parsing the
+	// JWT twice. It holds a *jose.JSONWebSignature value.
+	parsedJWTKey
+	discoveryLocationsKey
+	cacheKey
+)
 
 // ClientContext returns a new Context that carries the provided HTTP client.
 //
@@ -79,11 +91,72 @@ func getClient(ctx context.Context) *http.Client {
 //	provider, err := oidc.NewProvider(ctx, discoveryBaseURL)
 //
 // This is insecure because validating the correct issuer is critical for multi-tenant
-// providers. Any overrides here MUST be carefully reviewed.
+// providers. Any overrides here MUST be carefully reviewed. To accept tokens from
+// several tenants of such a provider, see Config.IssuerTemplate.
 func InsecureIssuerURLContext(ctx context.Context, issuerURL string) context.Context {
 	return context.WithValue(ctx, issuerURLKey, issuerURL)
 }
 
+// DiscoveryLocation identifies a well-known URL providers publish their metadata
+// at.
+type DiscoveryLocation int
+
+const (
+	// OpenIDConfiguration is the location defined by OpenID Connect Discovery.
+	// The path "/.well-known/openid-configuration" is appended to the issuer.
+	//
+	// See: https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderConfig
+	OpenIDConfiguration DiscoveryLocation = iota + 1
+	// OAuthAuthorizationServer is the location defined by OAuth 2.0 Authorization
+	// Server Metadata. The path "/.well-known/oauth-authorization-server" is
+	// inserted between the host and any path component of the issuer.
+	//
+	// See: https://www.rfc-editor.org/rfc/rfc8414#section-3
+	OAuthAuthorizationServer
+)
+
+// defaultDiscoveryLocations are tried in order when the context doesn't
+// specify any locations.
+var defaultDiscoveryLocations = []DiscoveryLocation{OpenIDConfiguration, OAuthAuthorizationServer}
+
+// wellKnownURL returns the URL the issuer's metadata is published at.
+func (l DiscoveryLocation) wellKnownURL(issuer string) (string, error) {
+	switch l {
+	case OpenIDConfiguration:
+		return strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration", nil
+	case OAuthAuthorizationServer:
+		u, err := url.Parse(issuer)
+		if err != nil {
+			return "", fmt.Errorf("oidc: invalid issuer %q: %v", issuer, err)
+		}
+		u.Path = "/.well-known/oauth-authorization-server" + strings.TrimSuffix(u.Path, "/")
+		u.RawPath = ""
+		return u.String(), nil
+	default:
+		return "", fmt.Errorf("oidc: unknown discovery location %d", l)
+	}
+}
+
+// DiscoveryLocationsContext returns a new Context that configures the
+// well-known locations NewProvider fetches metadata from, in order. The first
+// location that returns a valid document with a matching issuer is used.
+//
+// By default, NewProvider tries OpenIDConfiguration, then OAuthAuthorizationServer.
+//
+//	// Only use RFC 8414 discovery.
+//	ctx := oidc.DiscoveryLocationsContext(parentContext, oidc.OAuthAuthorizationServer)
+//	provider, err := oidc.NewProvider(ctx, "https://auth.example.com/tenant1")
+func DiscoveryLocationsContext(ctx context.Context, locations ...DiscoveryLocation) context.Context {
+	return context.WithValue(ctx, discoveryLocationsKey, locations)
+}
+
+func getDiscoveryLocations(ctx context.Context) []DiscoveryLocation {
+	if l, ok := ctx.Value(discoveryLocationsKey).([]DiscoveryLocation); ok && len(l) > 0 {
+		return l
+	}
+	return defaultDiscoveryLocations
+}
+
 func doRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
 	client := http.DefaultClient
 	if c := getClient(ctx); c != nil {
@@ -94,6 +167,10 @@ func doRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
 
 // Provider represents an OpenID Connect server's configuration.
 type Provider struct {
+	// Guards the discovered fields below, which are replaced when a refreshing
+	// provider re-fetches its discovery document.
+	discoveryMu sync.RWMutex
+
 	issuer        string
 	authURL       string
 	tokenURL      string
@@ -102,14 +179,23 @@ type Provider struct {
 	jwksURL       string
 	algorithms    []string
 
-	// Raw claims returned by the server.
+	// Raw claims returned by the server, and their decoded form.
 	rawClaims []byte
+	metadata  *ProviderMetadata
+
+	// Stops refreshing the discovery document, and is closed once the refreshing
+	// goroutine exits. Only set by NewRefreshingProvider.
+	stopRefreshing context.CancelFunc
+	refreshDone    chan struct{}
 
 	// Guards all of the following fields.
 	mu sync.Mutex
 	// HTTP client specified from the initial NewProvider request. This is used
 	// when creating the common key set.
 	client *http.Client
+	// Cache specified from the initial NewProvider request. This is used when
+	// creating the common key set.
+	cache Cache
 	// A key set that uses context.Background() and is shared between all code paths
 	// that don't have a convinent way of supplying a unique context.
 	commonRemoteKeySet KeySet
@@ -123,11 +209,55 @@ func (p *Provider) remoteKeySet() KeySet {
 		if p.client != nil {
 			ctx = ClientContext(ctx, p.client)
 		}
-		p.commonRemoteKeySet = NewRemoteKeySet(ctx, p.jwksURL)
+		if p.cache != nil {
+			ctx = CacheContext(ctx, p.cache)
+		}
+		p.commonRemoteKeySet = p.newRemoteKeySet(ctx)
 	}
 	return p.commonRemoteKeySet
 }
 
+// newRemoteKeySet returns a key set for the provider's jwks_uri. Key sets of
+// refreshing providers follow changes to the jwks_uri.
+func (p *Provider) newRemoteKeySet(ctx context.Context) KeySet {
+	if p.stopRefreshing != nil {
+		return &refreshingKeySet{provider: p, ctx: ctx}
+	}
+	p.discoveryMu.RLock()
+	defer p.discoveryMu.RUnlock()
+	return NewRemoteKeySet(ctx, p.jwksURL)
+}
+
+// refreshingKeySet is a KeySet that recreates its remote key set when the
+// jwks_uri of a refreshing provider changes.
+type refreshingKeySet struct {
+	provider *Provider
+	ctx      context.Context
+
+	mu      sync.Mutex
+	jwksURL string
+	keySet  *RemoteKeySet
+}
+
+func (r *refreshingKeySet) VerifySignature(ctx context.Context, jwt string) ([]byte, error) {
+	r.provider.discoveryMu.RLock()
+	jwksURL := r.provider.jwksURL
+	r.provider.discoveryMu.RUnlock()
+
+	r.mu.Lock()
+	if r.keySet == nil || r.jwksURL != jwksURL {
+		r.jwksURL = jwksURL
+		r.keySet = NewRemoteKeySet(r.ctx, jwksURL)
+	}
+	keySet := r.keySet
+	r.mu.Unlock()
+
+	return keySet.VerifySignature(ctx, jwt)
+}
+
+// providerJSON holds the fields of a discovery document NewProvider requires.
+// They're decoded separately from ProviderMetadata, so optional fields with
+// unexpected types don't cause discovery to fail.
 type providerJSON struct {
 	Issuer        string   `json:"issuer"`
 	AuthURL       string   `json:"authorization_endpoint"`
@@ -179,6 +309,21 @@ type ProviderConfig struct {
 	// verify issued ID tokens. This endpoint is polled as new keys are made
 	// available.
 	JWKSURL string
+	// IntrospectionURL is the endpoint used by the provider to support OAuth 2.0
+	// token introspection.
+	//
+	// https://www.rfc-editor.org/rfc/rfc7662
+	IntrospectionURL string
+	// RevocationURL is the endpoint used by the provider to support OAuth 2.0
+	// token revocation.
+	//
+	// https://www.rfc-editor.org/rfc/rfc7009
+	RevocationURL string
+	// EndSessionURL is the endpoint used by the provider to support RP-Initiated
+	// Logout.
+	//
+	// https://openid.net/specs/openid-connect-rpinitiated-1_0.html
+	EndSessionURL string
 
 	// Algorithms, if provided, indicate a list of JWT algorithms allowed to sign
 	// ID tokens. If not provided, this defaults to the algorithms advertised by
@@ -197,7 +342,20 @@ func (p *ProviderConfig) NewProvider(ctx context.Context) *Provider {
 		userInfoURL:   p.UserInfoURL,
 		jwksURL:       p.JWKSURL,
 		algorithms:    p.Algorithms,
-		client:        getClient(ctx),
+		metadata: &ProviderMetadata{
+			Issuer:                           p.IssuerURL,
+			AuthURL:                          p.AuthURL,
+			TokenURL:                         p.TokenURL,
+			DeviceAuthURL:                    p.DeviceAuthURL,
+			UserInfoURL:                      p.UserInfoURL,
+			JWKSURL:                          p.JWKSURL,
+			IntrospectionURL:                 p.IntrospectionURL,
+			RevocationURL:                    p.RevocationURL,
+			EndSessionURL:                    p.EndSessionURL,
+			IDTokenSigningAlgValuesSupported: p.Algorithms,
+		},
+		client: getClient(ctx),
+		cache:  getCache(ctx),
 	}
 }
 
@@ -205,57 +363,176 @@ func (p *ProviderConfig) NewProvider(ctx context.Context) *Provider {
 //
 // The issuer is the URL identifier for the service. For example: "https://accounts.google.com"
 // or "https://login.salesforce.com".
+//
+// If the issuer doesn't publish an OpenID Connect discovery document, NewProvider
+// falls back to OAuth 2.0 Authorization Server Metadata (RFC 8414). Use
+// DiscoveryLocationsContext to change which locations are tried, and in which order.
+//
+// If the context carries a Cache through CacheContext, a fresh discovery document
+// stored in the cache is used instead of making a request.
 func NewProvider(ctx context.Context, issuer string) (*Provider, error) {
-	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
-	req, err := http.NewRequest("GET", wellKnown, nil)
-	if err != nil {
-		return nil, err
+	p := &Provider{
+		client: getClient(ctx),
+		cache:  getCache(ctx),
 	}
-	resp, err := doRequest(ctx, req)
-	if err != nil {
+	if err := p.discover(ctx, issuer); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	return p, nil
+}
 
-	body, err := io.ReadAll(resp.Body)
+// NewRefreshingProvider constructs a Provider through discovery like NewProvider,
+// then re-fetches the discovery document every interval. This lets long running
+// programs pick up changes to the provider's endpoints and signing algorithms
+// without restarting.
+//
+// Refreshed documents are validated the same way as by NewProvider, including
+// the issuer check. If a refresh fails, the provider keeps its current metadata
+// and tries again at the next interval. Endpoints, claims, and verifiers created
+// by the provider always use the latest valid document, unless verifiers are
+// configured with explicit SupportedSigningAlgs.
+//
+// Refreshing stops when the context is canceled or Close is called. The interval
+// must be positive.
+func NewRefreshingProvider(ctx context.Context, issuer string, interval time.Duration) (*Provider, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("oidc: invalid refresh interval %v, must be positive", interval)
+	}
+	p, err := NewProvider(ctx, issuer)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read response body: %v", err)
+		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%s: %s", resp.Status, body)
-	}
+	ctx, cancel := context.WithCancel(ctx)
+	p.stopRefreshing = cancel
+	p.refreshDone = make(chan struct{})
 
-	var p providerJSON
-	err = unmarshalResp(resp, body, &p)
-	if err != nil {
-		return nil, fmt.Errorf("oidc: failed to decode provider discovery object: %v", err)
-	}
+	go func() {
+		defer close(p.refreshDone)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Errors are ignored, the current metadata stays in use.
+				p.discover(ctx, issuer)
+			}
+		}
+	}()
+	return p, nil
+}
 
+// Close stops refreshing the discovery document and waits for the refreshing
+// goroutine to exit. The provider remains usable with its latest metadata.
+//
+// Close only needs to be called for providers created with NewRefreshingProvider,
+// and is a no-op for others. It's safe to call multiple times.
+func (p *Provider) Close() {
+	if p.stopRefreshing == nil {
+		return
+	}
+	p.stopRefreshing()
+	<-p.refreshDone
+}
+
+// discover fetches and validates the issuer's discovery document, then replaces
+// the provider's metadata with it.
+func (p *Provider) discover(ctx context.Context, issuer string) error {
 	issuerURL, skipIssuerValidation := ctx.Value(issuerURLKey).(string)
 	if !skipIssuerValidation {
 		issuerURL = issuer
 	}
-	if p.Issuer != issuerURL && !skipIssuerValidation {
-		return nil, fmt.Errorf("oidc: issuer did not match the issuer returned by provider, expected %q got %q", issuer, p.Issuer)
+
+	var (
+		pj   *providerJSON
+		body []byte
+		errs []error
+	)
+	locations := getDiscoveryLocations(ctx)
+	for _, l := range locations {
+		wellKnown, err := l.wellKnownURL(issuer)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pj, body, err = getProviderMetadata(ctx, wellKnown)
+		if err == nil && pj.Issuer != issuerURL && !skipIssuerValidation {
+			err = fmt.Errorf("oidc: issuer did not match the issuer returned by provider, expected %q got %q", issuer, pj.Issuer)
+		}
+		if err == nil {
+			break
+		}
+		if len(locations) > 1 {
+			err = fmt.Errorf("%s: %w", wellKnown, err)
+		}
+		errs = append(errs, err)
+		pj = nil
+	}
+	if pj == nil {
+		return errors.Join(errs...)
 	}
+
 	var algs []string
-	for _, a := range p.Algorithms {
+	for _, a := range pj.Algorithms {
 		if supportedAlgorithms[a] {
 			algs = append(algs, a)
 		}
 	}
-	return &Provider{
-		issuer:        issuerURL,
-		authURL:       p.AuthURL,
-		tokenURL:      p.TokenURL,
-		deviceAuthURL: p.DeviceAuthURL,
-		userInfoURL:   p.UserInfoURL,
-		jwksURL:       p.JWKSURL,
-		algorithms:    algs,
-		rawClaims:     body,
-		client:        getClient(ctx),
-	}, nil
+
+	p.discoveryMu.Lock()
+	defer p.discoveryMu.Unlock()
+	p.issuer = issuerURL
+	p.authURL = pj.AuthURL
+	p.tokenURL = pj.TokenURL
+	p.deviceAuthURL = pj.DeviceAuthURL
+	p.userInfoURL = pj.UserInfoURL
+	p.jwksURL = pj.JWKSURL
+	p.algorithms = algs
+	p.rawClaims = body
+	p.metadata = parseProviderMetadata(body)
+	return nil
+}
+
+// getProviderMetadata fetches the discovery document at the well-known URL, or
+// loads it from the context's cache if it's fresh.
+func getProviderMetadata(ctx context.Context, wellKnown string) (*providerJSON, []byte, error) {
+	cache := getCache(ctx)
+	if entry := cacheLoad(ctx, cache, wellKnown, time.Now(), 0); entry != nil {
+		var p providerJSON
+		if err := json.Unmarshal(entry.Data, &p); err == nil {
+			return &p, entry.Data, nil
+		}
+	}
+
+	req, err := http.NewRequest("GET", wellKnown, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := doRequest(ctx, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to read response body: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("%s: %s", resp.Status, body)
+	}
+
+	var p providerJSON
+	err = unmarshalResp(resp, body, &p)
+	if err != nil {
+		return nil, nil, fmt.Errorf("oidc: failed to decode provider discovery object: %v", err)
+	}
+	cacheStore(ctx, cache, wellKnown, body, cacheExpiry(resp.Header, time.Now()))
+	return &p, body, nil
 }
 
 // Claims unmarshals raw fields returned by the server during discovery.
@@ -271,24 +548,54 @@ func NewProvider(ctx context.Context, issuer string) (*Provider, error) {
 //
 // For a list of fields defined by the OpenID Connect spec see:
 // https://openid.net/specs/openid-connect-discovery-1_0.html#ProviderMetadata
+//
+// Standard fields are also available through the Metadata method.
 func (p *Provider) Claims(v interface{}) error {
-	if p.rawClaims == nil {
+	p.discoveryMu.RLock()
+	rawClaims := p.rawClaims
+	p.discoveryMu.RUnlock()
+
+	if rawClaims == nil {
 		return errors.New("oidc: claims not set")
 	}
-	return json.Unmarshal(p.rawClaims, v)
+	return json.Unmarshal(rawClaims, v)
+}
+
+// Metadata returns the provider metadata advertised through discovery. For
+// providers created from a ProviderConfig, it only holds the configured values.
+// Members of the discovery document with an unexpected type are ignored, and
+// remain available through Claims.
+//
+// The returned value must not be modified.
+func (p *Provider) Metadata() *ProviderMetadata {
+	p.discoveryMu.RLock()
+	defer p.discoveryMu.RUnlock()
+	return p.metadata
 }
 
 // Endpoint returns the OAuth2 auth and token endpoints for the given provider.
 func (p *Provider) Endpoint() oauth2.Endpoint {
+	p.discoveryMu.RLock()
+	defer p.discoveryMu.RUnlock()
 	return oauth2.Endpoint{AuthURL: p.authURL, DeviceAuthURL: p.deviceAuthURL, TokenURL: p.tokenURL}
 }
 
 // UserInfoEndpoint returns the OpenID Connect userinfo endpoint for the given
 // provider.
 func (p *Provider) UserInfoEndpoint() string {
+	p.discoveryMu.RLock()
+	defer p.discoveryMu.RUnlock()
 	return p.userInfoURL
 }
 
+// signingAlgorithms returns the supported ID token signing algorithms advertised
+// by the provider.
+func (p *Provider) signingAlgorithms() []string {
+	p.discoveryMu.RLock()
+	defer p.discoveryMu.RUnlock()
+	return p.algorithms
+}
+
 // UserInfo represents the OpenID Connect userinfo claims.
 type UserInfo struct {
 	Subject       string `json:"sub"`
@@ -319,11 +626,12 @@ func (u *UserInfo) Claims(v interface{}) error {
 
 // UserInfo uses the token source to query the provider's user info endpoint.
 func (p *Provider) UserInfo(ctx context.Context, tokenSource oauth2.TokenSource) (*UserInfo, error) {
-	if p.userInfoURL == "" {
+	userInfoURL := p.UserInfoEndpoint()
+	if userInfoURL == "" {
 		return nil, errors.New("oidc: user info endpoint is not supported by this provider")
 	}
 
-	req, err := http.NewRequest("GET", p.userInfoURL, nil)
+	req, err := http.NewRequest("GET", userInfoURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("oidc: create GET request: %v", err)
 	}
@@ -392,6 +700,13 @@ type IDToken struct {
 	// This package ensures the audience contains an expected value.
 	Audience []string
 
+	// The client ID of the party the token was issued to (azp claim), if set.
+	// Providers must set this when the audience has multiple values.
+	//
+	// This package ensures the authorized party, if present, matches the expected
+	// client ID.
+	AuthorizedParty string
+
 	// A unique string which identifies the end user.
 	Subject string
 
@@ -403,14 +718,42 @@ type IDToken struct {
 
 	// Initial nonce provided during the authentication redirect.
 	//
-	// This package does NOT provided verification on the value of this field
-	// and it's the user's responsibility to ensure it contains a valid value.
+	// This package only verifies the value of this field if the ExpectNonce
+	// option is passed to VerifyWithOptions. Otherwise it's the user's
+	// responsibility to ensure it contains a valid value.
 	Nonce string
 
+	// Time when the end user authenticated (auth_time claim), or the zero value
+	// if the provider didn't include it or its value is malformed.
+	AuthTime time.Time
+
+	// Authentication context class reference (acr claim), if set and well formed.
+	// This identifies the level of assurance of the authentication, such as
+	// multi-factor.
+	ACR string
+
+	// Authentication methods references (amr claim), if set and well formed. For
+	// example "pwd" or "otp".
+	//
+	// See: https://www.rfc-editor.org/rfc/rfc8176
+	AMR []string
+
 	// at_hash claim, if set in the ID token. Callers can verify an access token
 	// that corresponds to the ID token using the VerifyAccessToken method.
 	AccessTokenHash string
 
+	// c_hash claim, if set in the ID token. Callers can verify the authorization
+	// code returned with the ID token using the VerifyAuthorizationCode method.
+	CodeHash string
+
+	// s_hash claim, if set in the ID token. Callers can verify the state returned
+	// with the ID token using the VerifyState method.
+	StateHash string
+
+	// Header of the token. Populated if the token's header could be parsed, which
+	// is always the case if its signature was verified.
+	Header JOSEHeader
+
 	// signature algorithm used for ID token, needed to compute a verification hash of an
 	// access token
 	sigAlgorithm string
@@ -420,6 +763,11 @@ type IDToken struct {
 
 	// Map of distributed claim names to claim sources
 	distributedClaims map[string]claimSource
+
+	// Time function and clock skew of the verifier, used to check the exp and
+	// nbf claims of claims JWTs.
+	now       func() time.Time
+	clockSkew time.Duration
 }
 
 // Claims unmarshals the raw JSON payload of the ID Token into a provided struct.
@@ -442,6 +790,80 @@ func (i *IDToken) Claims(v interface{}) error {
 	return json.Unmarshal(i.claims, v)
 }
 
+// ResolveClaims fetches the token's distributed claims from their endpoints and
+// verifies the signed JWTs of its aggregated claims, then merges the claims into the
+// payload returned by Claims. The _claim_names and _claim_sources members are removed
+// once all claims are resolved.
+//
+// Claims may come from several Claims Providers, which may be different from the
+// provider that issued the ID Token. keySets maps the issuer of each trusted Claims
+// Provider to the key set used to verify its claims. Each claims JWT must have an
+// iss claim found in keySets and a valid signature, other claims such as exp and
+// aud are optional. If set, exp and nbf are checked with the Now and ClockSkew of
+// the verifier's Config. Requests to distributed claim endpoints use the HTTP
+// client of the context if one is set using ClientContext.
+//
+//	err := idToken.ResolveClaims(ctx, map[string]oidc.KeySet{
+//		"https://claims.example.com": oidc.NewRemoteKeySet(ctx, "https://claims.example.com/keys"),
+//	})
+//
+// If resolving any claim fails, the token's claims are left unmodified.
+//
+// See: https://openid.net/specs/openid-connect-core-1_0.html#AggregatedDistributedClaims
+func (i *IDToken) ResolveClaims(ctx context.Context, keySets map[string]KeySet) error {
+	if len(i.distributedClaims) == 0 {
+		return nil
+	}
+	var claims map[string]json.RawMessage
+	if err := json.Unmarshal(i.claims, &claims); err != nil {
+		return fmt.Errorf("oidc: failed to unmarshal claims: %v", err)
+	}
+
+	now := time.Now
+	if i.now != nil {
+		now = i.now
+	}
+	nowTime := now()
+
+	// Sources may provide several claims, only resolve each of them once.
+	resolved := make(map[claimSource]map[string]json.RawMessage)
+	for name, src := range i.distributedClaims {
+		srcClaims, ok := resolved[src]
+		if !ok {
+			rawJWT := src.JWT
+			if rawJWT == "" {
+				var err error
+				if rawJWT, err = fetchDistributedClaim(ctx, src); err != nil {
+					return fmt.Errorf("oidc: resolving claim %q: %v", name, err)
+				}
+			}
+			payload, err := verifyClaimsJWT(ctx, keySets, rawJWT, nowTime, i.clockSkew)
+			if err != nil {
+				return fmt.Errorf("oidc: resolving claim %q: %v", name, err)
+			}
+			if err := json.Unmarshal(payload, &srcClaims); err != nil {
+				return fmt.Errorf("oidc: failed to unmarshal claims for %q: %v", name, err)
+			}
+			resolved[src] = srcClaims
+		}
+		value, ok := srcClaims[name]
+		if !ok {
+			return fmt.Errorf("oidc: claim source for %q does not contain the claim", name)
+		}
+		claims[name] = value
+	}
+	delete(claims, "_claim_names")
+	delete(claims, "_claim_sources")
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return fmt.Errorf("oidc: failed to marshal claims: %v", err)
+	}
+	i.claims = payload
+	i.distributedClaims = map[string]claimSource{}
+	return nil
+}
+
 // VerifyAccessToken verifies that the hash of the access token that corresponds to the iD token
 // matches the hash in the id token. It returns an error if the hashes  don't match.
 // It is the caller's responsibility to ensure that the optional access token hash is present for the ID token
@@ -450,6 +872,34 @@ func (i *IDToken) VerifyAccessToken(accessToken string) error {
 	if i.AccessTokenHash == "" {
 		return errNoAtHash
 	}
+	return i.verifyHash(accessToken, i.AccessTokenHash, errInvalidAtHash)
+}
+
+// VerifyAuthorizationCode verifies that the hash of the authorization code returned with
+// the ID token from the authorization endpoint matches the c_hash claim, as required
+// by the hybrid flow. It returns an error if the claim is missing or the hashes don't match.
+// See https://openid.net/specs/openid-connect-core-1_0.html#HybridIDToken
+func (i *IDToken) VerifyAuthorizationCode(code string) error {
+	if i.CodeHash == "" {
+		return errNoCodeHash
+	}
+	return i.verifyHash(code, i.CodeHash, errInvalidCodeHash)
+}
+
+// VerifyState verifies that the hash of the state parameter returned with the ID token
+// from the authorization endpoint matches the s_hash claim. It returns an error if the
+// claim is missing or the hashes don't match.
+// See https://openid.net/specs/openid-financial-api-part-2-1_0.html#id-token-as-detached-signature
+func (i *IDToken) VerifyState(state string) error {
+	if i.StateHash == "" {
+		return errNoStateHash
+	}
+	return i.verifyHash(state, i.StateHash, errInvalidStateHash)
+}
+
+// verifyHash computes the left-most half of the hash of value using the hash function
+// of the ID token's signing algorithm, and compares it to the base64url encoded want.
+func (i *IDToken) verifyHash(value, want string, errMismatch error) error {
 	var h hash.Hash
 	switch i.sigAlgorithm {
 	case RS256, ES256, PS256:
@@ -461,31 +911,49 @@ func (i *IDToken) VerifyAccessToken(accessToken string) error {
 	default:
 		return fmt.Errorf("oidc: unsupported signing algorithm %q", i.sigAlgorithm)
 	}
-	h.Write([]byte(accessToken)) // hash documents that Write will never return an error
+	h.Write([]byte(value)) // hash documents that Write will never return an error
 	sum := h.Sum(nil)[:h.Size()/2]
 	actual := base64.RawURLEncoding.EncodeToString(sum)
-	if actual != i.AccessTokenHash {
-		return errInvalidAtHash
+	if actual != want {
+		return errMismatch
 	}
 	return nil
 }
 
 type idToken struct {
-	Issuer       string                 `json:"iss"`
-	Subject      string                 `json:"sub"`
-	Audience     audience               `json:"aud"`
-	Expiry       jsonTime               `json:"exp"`
-	IssuedAt     jsonTime               `json:"iat"`
-	NotBefore    *jsonTime              `json:"nbf"`
-	Nonce        string                 `json:"nonce"`
-	AtHash       string                 `json:"at_hash"`
-	ClaimNames   map[string]string      `json:"_claim_names"`
-	ClaimSources map[string]claimSource `json:"_claim_sources"`
+	Issuer          string                 `json:"iss"`
+	Subject         string                 `json:"sub"`
+	Audience        audience               `json:"aud"`
+	AuthorizedParty string                 `json:"azp"`
+	Expiry          jsonTime               `json:"exp"`
+	IssuedAt        jsonTime               `json:"iat"`
+	NotBefore       *jsonTime              `json:"nbf"`
+	Nonce           string                 `json:"nonce"`
+	AuthTime        json.RawMessage        `json:"auth_time"`
+	ACR             json.RawMessage        `json:"acr"`
+	AMR             json.RawMessage        `json:"amr"`
+	AtHash          string                 `json:"at_hash"`
+	CHash           string                 `json:"c_hash"`
+	SHash           string                 `json:"s_hash"`
+	TenantID        string                 `json:"tid"`
+	ClaimNames      map[string]string      `json:"_claim_names"`
+	ClaimSources    map[string]claimSource `json:"_claim_sources"`
+}
+
+// unmarshalOptional decodes a claim which may be absent. Absent and null claims
+// leave v unchanged.
+func unmarshalOptional(raw json.RawMessage, v interface{}) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	return json.Unmarshal(raw, v)
 }
 
 type claimSource struct {
 	Endpoint    string `json:"endpoint"`
 	AccessToken string `json:"access_token"`
+	// Signed claims of an aggregated claim source.
+	JWT string `json:"JWT"`
 }
 
 type stringAsBool bool
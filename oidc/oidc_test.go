@@ -11,7 +11,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -114,6 +116,60 @@ func TestAccessTokenVerification(t *testing.T) {
 	}
 }
 
+func TestHybridHashVerification(t *testing.T) {
+	// The hash algorithm doesn't depend on the claim, so reuse the values
+	// computed for the access token.
+	tok := &IDToken{
+		sigAlgorithm: googleSigningAlg,
+		CodeHash:     googleAccessTokenHash,
+		StateHash:    googleAccessTokenHash,
+	}
+	if err := tok.VerifyAuthorizationCode(googleAccessToken); err != nil {
+		t.Errorf("VerifyAuthorizationCode() failed: %v", err)
+	}
+	if err := tok.VerifyState(googleAccessToken); err != nil {
+		t.Errorf("VerifyState() failed: %v", err)
+	}
+	if err := tok.VerifyAuthorizationCode("other-code"); err != errInvalidCodeHash {
+		t.Errorf("VerifyAuthorizationCode() with wrong code, want %v got %v", errInvalidCodeHash, err)
+	}
+	if err := tok.VerifyState("other-state"); err != errInvalidStateHash {
+		t.Errorf("VerifyState() with wrong state, want %v got %v", errInvalidStateHash, err)
+	}
+
+	noHashes := &IDToken{sigAlgorithm: googleSigningAlg}
+	if err := noHashes.VerifyAuthorizationCode(googleAccessToken); err != errNoCodeHash {
+		t.Errorf("VerifyAuthorizationCode() without c_hash, want %v got %v", errNoCodeHash, err)
+	}
+	if err := noHashes.VerifyState(googleAccessToken); err != errNoStateHash {
+		t.Errorf("VerifyState() without s_hash, want %v got %v", errNoStateHash, err)
+	}
+}
+
+func TestHybridHashPreserved(t *testing.T) {
+	cHash := "LDktKdoQak3Pk0cnXxCltA"
+	sHash := "piwt8oCH-K2D9pXlaS1Y-w"
+	vt := verificationTest{
+		name:    "preserves code and state hashes",
+		idToken: `{"iss":"https://foo","aud":"client1","c_hash":"` + cHash + `","s_hash":"` + sHash + `"}`,
+		config: Config{
+			ClientID:        "client1",
+			SkipExpiryCheck: true,
+		},
+		signKey: newRSAKey(t),
+	}
+	tok, err := vt.runGetToken(t)
+	if err != nil {
+		t.Fatalf("parsing token: %v", err)
+	}
+	if tok.CodeHash != cHash {
+		t.Errorf("code hash not preserved correctly, want %q got %q", cHash, tok.CodeHash)
+	}
+	if tok.StateHash != sHash {
+		t.Errorf("state hash not preserved correctly, want %q got %q", sHash, tok.StateHash)
+	}
+}
+
 func TestNewProvider(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -675,3 +731,214 @@ func TestVerifierAlg(t *testing.T) {
 	}
 
 }
+
+func TestRefreshingProvider(t *testing.T) {
+	key1 := newRSAKey(t)
+	key2 := newRSAKey(t)
+
+	var (
+		mu       sync.Mutex
+		issuer   string
+		tokenURL = "/token"
+		keysPath = "/keys1"
+		// Number of discovery requests served.
+		discoveries int
+	)
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			discoveries++
+			fmt.Fprintf(w, `{"issuer":%q,"token_endpoint":%q,"jwks_uri":%q,"id_token_signing_alg_values_supported":["RS256"]}`,
+				issuer, issuer+tokenURL, issuer+keysPath)
+		case "/keys1":
+			(&keyServer{keys: jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key1.jwk()}}}).ServeHTTP(w, r)
+		case "/keys2":
+			(&keyServer{keys: jose.JSONWebKeySet{Keys: []jose.JSONWebKey{key2.jwk()}}}).ServeHTTP(w, r)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	issuer = s.URL
+
+	ctx := context.Background()
+	p, err := NewRefreshingProvider(ctx, issuer, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("new provider: %v", err)
+	}
+	defer p.Close()
+
+	verifier := p.Verifier(&Config{ClientID: "client"})
+	payload := []byte(`{"iss":"` + issuer + `","aud":"client","exp":` + strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10) + `}`)
+	if _, err := verifier.Verify(ctx, key1.sign(t, payload)); err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+
+	// Move the token endpoint and keys.
+	mu.Lock()
+	tokenURL = "/v2/token"
+	keysPath = "/keys2"
+	mu.Unlock()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for p.Endpoint().TokenURL != issuer+"/v2/token" {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for provider refresh")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := verifier.Verify(ctx, key2.sign(t, payload)); err != nil {
+		t.Errorf("verify with new jwks_uri: %v", err)
+	}
+	if _, err := verifier.Verify(ctx, key1.sign(t, payload)); err == nil {
+		t.Errorf("incorrectly verified token signed by key from old jwks_uri")
+	}
+
+	// Refreshes with a different issuer are rejected.
+	mu.Lock()
+	issuer = "https://attacker.example.com"
+	switched := discoveries
+	mu.Unlock()
+	// Refreshes run one at a time, so once a second request after the switch has
+	// been served, at least one document with the new issuer has been processed.
+	deadline = time.Now().Add(10 * time.Second)
+	for {
+		mu.Lock()
+		n := discoveries - switched
+		mu.Unlock()
+		if n >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for provider refresh")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	p.Close()
+
+	if got := p.Endpoint().TokenURL; got != s.URL+"/v2/token" {
+		t.Errorf("expected metadata with mismatched issuer to be ignored, got token endpoint %q", got)
+	}
+}
+
+func TestRefreshingProviderInvalidInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Minute} {
+		// Fails before making any requests.
+		if _, err := NewRefreshingProvider(context.Background(), "https://example.com", interval); err == nil {
+			t.Errorf("expected error for refresh interval %v", interval)
+		}
+	}
+}
+
+func TestDiscoveryLocations(t *testing.T) {
+	tests := []struct {
+		name      string
+		path      string
+		locations []DiscoveryLocation
+		// Paths served by the test server, mapped to the token endpoint they return.
+		documents    map[string]string
+		wantTokenURL string
+		wantErr      bool
+	}{
+		{
+			name: "openid configuration preferred",
+			documents: map[string]string{
+				"/.well-known/openid-configuration":       "/oidc-token",
+				"/.well-known/oauth-authorization-server": "/oauth-token",
+			},
+			wantTokenURL: "/oidc-token",
+		},
+		{
+			name: "fallback to oauth authorization server",
+			documents: map[string]string{
+				"/.well-known/oauth-authorization-server": "/oauth-token",
+			},
+			wantTokenURL: "/oauth-token",
+		},
+		{
+			name: "oauth authorization server path insertion",
+			path: "/tenant1",
+			documents: map[string]string{
+				"/.well-known/oauth-authorization-server/tenant1": "/tenant1/token",
+			},
+			wantTokenURL: "/tenant1/token",
+		},
+		{
+			name:      "configured order",
+			locations: []DiscoveryLocation{OAuthAuthorizationServer, OpenIDConfiguration},
+			documents: map[string]string{
+				"/.well-known/openid-configuration":       "/oidc-token",
+				"/.well-known/oauth-authorization-server": "/oauth-token",
+			},
+			wantTokenURL: "/oauth-token",
+		},
+		{
+			name:      "configured locations only",
+			locations: []DiscoveryLocation{OpenIDConfiguration},
+			documents: map[string]string{
+				"/.well-known/oauth-authorization-server": "/oauth-token",
+			},
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var issuer string
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tokenPath, ok := test.documents[r.URL.Path]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				fmt.Fprintf(w, `{"issuer":%q,"token_endpoint":"%s%s"}`, issuer, issuer, tokenPath)
+			}))
+			defer s.Close()
+			issuer = s.URL + test.path
+
+			ctx := context.Background()
+			if test.locations != nil {
+				ctx = DiscoveryLocationsContext(ctx, test.locations...)
+			}
+			p, err := NewProvider(ctx, issuer)
+			if err != nil {
+				if !test.wantErr {
+					t.Fatalf("NewProvider() failed: %v", err)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatalf("NewProvider(): expected error")
+			}
+			if got, want := p.Endpoint().TokenURL, issuer+test.wantTokenURL; got != want {
+				t.Errorf("NewProvider() unexpected tokenURL value, got=%s, want=%s", got, want)
+			}
+		})
+	}
+}
+
+func TestDiscoveryLocationIssuerMismatch(t *testing.T) {
+	// An issuer mismatch at the first location isn't masked by a later location.
+	var issuer string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			io.WriteString(w, `{"issuer":"https://attacker.example.com"}`)
+		case "/.well-known/oauth-authorization-server":
+			fmt.Fprintf(w, `{"issuer":%q}`, "https://other.example.com")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+	issuer = s.URL
+
+	_, err := NewProvider(context.Background(), issuer)
+	if err == nil {
+		t.Fatalf("NewProvider(): expected error")
+	}
+	if !strings.Contains(err.Error(), "https://attacker.example.com") {
+		t.Errorf("expected error to report mismatched issuer, got %v", err)
+	}
+}
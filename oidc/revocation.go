@@ -0,0 +1,125 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// Token type hints for introspection and revocation requests, which help the
+// provider look up the token.
+//
+// See: https://www.rfc-editor.org/rfc/rfc7009#section-2.1
+const (
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+)
+
+// OAuth 2.0 error codes returned by the provider's endpoints.
+//
+// See: https://www.rfc-editor.org/rfc/rfc6749#section-5.2
+const (
+	ErrorInvalidRequest       = "invalid_request"
+	ErrorInvalidClient        = "invalid_client"
+	ErrorInvalidGrant         = "invalid_grant"
+	ErrorUnauthorizedClient   = "unauthorized_client"
+	ErrorUnsupportedGrantType = "unsupported_grant_type"
+	ErrorInvalidScope         = "invalid_scope"
+
+	// ErrorUnsupportedTokenType indicates the provider doesn't support revoking
+	// the type of token.
+	//
+	// See: https://www.rfc-editor.org/rfc/rfc7009#section-2.2.1
+	ErrorUnsupportedTokenType = "unsupported_token_type"
+)
+
+// OAuthError is an error response from one of the provider's OAuth 2.0 endpoints,
+// such as the introspection and revocation endpoints.
+//
+//	err := provider.Revoke(ctx, auth, refreshToken, oidc.TokenTypeHintRefreshToken)
+//	var oauthErr *oidc.OAuthError
+//	if errors.As(err, &oauthErr) && oauthErr.Code == oidc.ErrorUnsupportedTokenType {
+//		// provider can't revoke refresh tokens
+//	}
+type OAuthError struct {
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Code is the error code, such as "invalid_client". Empty if the response
+	// wasn't a JSON error response.
+	Code string
+	// Description is the human readable error description, if any.
+	Description string
+	// URI identifies a web page with information about the error, if any.
+	URI string
+	// Body is the raw response body.
+	Body []byte
+}
+
+func (e *OAuthError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("oidc: %s: %s", http.StatusText(e.StatusCode), e.Body)
+	}
+	if e.Description == "" {
+		return fmt.Sprintf("oidc: %s", e.Code)
+	}
+	return fmt.Sprintf("oidc: %s: %s", e.Code, e.Description)
+}
+
+// newOAuthError parses the error response of an OAuth 2.0 endpoint.
+func newOAuthError(statusCode int, body []byte) *OAuthError {
+	e := &OAuthError{StatusCode: statusCode, Body: body}
+	var resp struct {
+		Code        string `json:"error"`
+		Description string `json:"error_description"`
+		URI         string `json:"error_uri"`
+	}
+	if json.Unmarshal(body, &resp) == nil {
+		e.Code = resp.Code
+		e.Description = resp.Description
+		e.URI = resp.URI
+	}
+	return e
+}
+
+// Revoke asks the provider to revoke a token, authenticating with the client's
+// credentials. The tokenTypeHint, such as TokenTypeHintRefreshToken, may be empty.
+// Revoking a refresh token usually also revokes the access tokens issued with it.
+//
+// Revoking a token which is invalid or was already revoked succeeds. Error
+// responses from the provider are returned as an *OAuthError.
+//
+// See: https://www.rfc-editor.org/rfc/rfc7009
+func (p *Provider) Revoke(ctx context.Context, auth *ClientAuth, token, tokenTypeHint string) error {
+	md := p.Metadata()
+	if md == nil || md.RevocationURL == "" {
+		return errors.New("oidc: provider has no revocation endpoint")
+	}
+
+	form := url.Values{"token": {token}}
+	if tokenTypeHint != "" {
+		form.Set("token_type_hint", tokenTypeHint)
+	}
+	req, err := auth.newRequest(md.RevocationURL, form, md.RevocationEndpointAuthMethodsSupported)
+	if err != nil {
+		return err
+	}
+
+	resp, err := doRequest(ctx, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("unable to read response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newOAuthError(resp.StatusCode, body)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRevoke(t *testing.T) {
+	var gotToken, gotHint string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client1" || secret != "secret1" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"invalid_client","error_description":"bad credentials"}`))
+			return
+		}
+		gotToken, gotHint = r.PostFormValue("token"), r.PostFormValue("token_type_hint")
+		switch gotHint {
+		case TokenTypeHintAccessToken:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"unsupported_token_type"}`))
+		case "unavailable":
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+		}
+	}))
+	defer s.Close()
+
+	ctx := context.Background()
+	p := (&ProviderConfig{IssuerURL: s.URL, RevocationURL: s.URL + "/revoke"}).NewProvider(ctx)
+	auth := &ClientAuth{ClientID: "client1", ClientSecret: "secret1"}
+
+	if err := p.Revoke(ctx, auth, "refresh1", TokenTypeHintRefreshToken); err != nil {
+		t.Fatalf("Revoke() failed: %v", err)
+	}
+	if gotToken != "refresh1" || gotHint != TokenTypeHintRefreshToken {
+		t.Errorf("unexpected request, token=%q, hint=%q", gotToken, gotHint)
+	}
+	if err := p.Revoke(ctx, auth, "token1", ""); err != nil {
+		t.Fatalf("Revoke() without hint failed: %v", err)
+	}
+	if gotHint != "" {
+		t.Errorf("expected no token_type_hint, got %q", gotHint)
+	}
+
+	tests := []struct {
+		name           string
+		auth           *ClientAuth
+		hint           string
+		wantStatusCode int
+		wantCode       string
+	}{
+		{
+			name:           "unsupported token type",
+			auth:           auth,
+			hint:           TokenTypeHintAccessToken,
+			wantStatusCode: http.StatusBadRequest,
+			wantCode:       ErrorUnsupportedTokenType,
+		},
+		{
+			name:           "invalid client",
+			auth:           &ClientAuth{ClientID: "client1", ClientSecret: "wrong"},
+			wantStatusCode: http.StatusUnauthorized,
+			wantCode:       ErrorInvalidClient,
+		},
+		{
+			name:           "non-json error",
+			auth:           auth,
+			hint:           "unavailable",
+			wantStatusCode: http.StatusServiceUnavailable,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := p.Revoke(ctx, test.auth, "token1", test.hint)
+			var oauthErr *OAuthError
+			if !errors.As(err, &oauthErr) {
+				t.Fatalf("expected *OAuthError, got %v", err)
+			}
+			if oauthErr.StatusCode != test.wantStatusCode {
+				t.Errorf("unexpected status code, got=%d, want=%d", oauthErr.StatusCode, test.wantStatusCode)
+			}
+			if oauthErr.Code != test.wantCode {
+				t.Errorf("unexpected error code, got=%q, want=%q", oauthErr.Code, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestRevokeNoEndpoint(t *testing.T) {
+	p := (&ProviderConfig{IssuerURL: "https://example.com"}).NewProvider(context.Background())
+	if err := p.Revoke(context.Background(), &ClientAuth{ClientID: "client1"}, "token", ""); err == nil {
+		t.Errorf("expected error for provider without revocation endpoint")
+	}
+}
@@ -33,6 +33,65 @@ func (e *TokenExpiredError) Error() string {
 	return fmt.Sprintf("oidc: token is expired (Token Expiry: %v)", e.Expiry)
 }
 
+// NonceMismatchError indicates that Verify failed because the nonce of the token
+// didn't match the value passed to ExpectNonce.
+type NonceMismatchError struct {
+	// Expected is the nonce passed to ExpectNonce.
+	Expected string
+	// Got is the nonce of the token, or empty if the token had no nonce.
+	Got string
+}
+
+func (e *NonceMismatchError) Error() string {
+	if e.Got == "" {
+		return "oidc: id token has no nonce"
+	}
+	return fmt.Sprintf("oidc: id token nonce %q does not match expected value", e.Got)
+}
+
+// MaxAgeError indicates that Verify failed because the end user authenticated longer
+// ago than Config.MaxAge allows, or the token didn't have an auth_time claim.
+type MaxAgeError struct {
+	// AuthTime is the time the end user authenticated, or the zero value if the
+	// token had no auth_time claim.
+	AuthTime time.Time
+	// MaxAge is the maximum allowed time since authentication.
+	MaxAge time.Duration
+}
+
+func (e *MaxAgeError) Error() string {
+	if e.AuthTime.IsZero() {
+		return "oidc: id token has no auth_time claim"
+	}
+	return fmt.Sprintf("oidc: end user authenticated at %v, longer than the max age of %v ago", e.AuthTime, e.MaxAge)
+}
+
+// ACRError indicates that Verify failed because the acr claim of the token wasn't one
+// of Config.AcceptedACRValues.
+type ACRError struct {
+	// ACR is the acr claim of the token, or empty if the token had none.
+	ACR string
+	// Accepted is the set of accepted values.
+	Accepted []string
+}
+
+func (e *ACRError) Error() string {
+	return fmt.Sprintf("oidc: id token acr %q is not one of %q", e.ACR, e.Accepted)
+}
+
+// AMRError indicates that Verify failed because the amr claim of the token didn't
+// contain all of Config.RequiredAMRValues.
+type AMRError struct {
+	// AMR is the amr claim of the token.
+	AMR []string
+	// Missing is the set of required methods the token didn't contain.
+	Missing []string
+}
+
+func (e *AMRError) Error() string {
+	return fmt.Sprintf("oidc: id token amr %q is missing required methods %q", e.AMR, e.Missing)
+}
+
 // KeySet is a set of publc JSON Web Keys that can be used to validate the signature
 // of JSON web tokens. This is expected to be backed by a remote key set through
 // provider metadata discovery or an in-memory set of keys delivered out-of-band.
@@ -53,6 +112,10 @@ type IDTokenVerifier struct {
 	keySet KeySet
 	config *Config
 	issuer string
+
+	// Refreshing provider the verifier was created from, if any. Supplies the
+	// signing algorithms when the config doesn't specify them.
+	provider *Provider
 }
 
 // NewVerifier returns a verifier manually constructed from a key set and issuer URL.
@@ -95,6 +158,19 @@ type Config struct {
 	// If true, token expiry is not checked.
 	SkipExpiryCheck bool
 
+	// If specified, the typ header of the token must be one of these values. Values
+	// are compared as media types, so "JWT" also matches "application/jwt". Include
+	// an empty string to accept tokens without a typ header.
+	//
+	// Many providers don't set typ on ID Tokens, or set it to "JWT".
+	AllowedTypes []string
+	// If true, tokens whose typ header identifies them as something other than an
+	// ID Token, for instance an access token ("at+jwt") or logout token
+	// ("logout+jwt"), are rejected. Only tokens without a typ header or with typ
+	// "JWT" are accepted. This prevents other JWTs signed by the provider from
+	// being accepted as ID Tokens.
+	StrictTypeCheck bool
+
 	// SkipIssuerCheck is intended for specialized cases where the the caller wishes to
 	// defer issuer validation. When enabled, callers MUST independently verify the Token's
 	// Issuer is a known good value.
@@ -104,9 +180,55 @@ type Config struct {
 	// this option.
 	SkipIssuerCheck bool
 
+	// IssuerTemplate allows tokens from multi-tenant providers, such as Azure AD, whose
+	// issuer contains the tenant ID. When set, the token's issuer must match the
+	// template with the "{tenantid}" placeholder replaced by a single path segment,
+	// and the token's tid claim must equal that segment. The verifier's issuer URL
+	// is ignored.
+	//
+	//	provider, err := oidc.NewProvider(
+	//		oidc.InsecureIssuerURLContext(ctx, "https://login.microsoftonline.com/{tenantid}/v2.0"),
+	//		"https://login.microsoftonline.com/organizations/v2.0",
+	//	)
+	//	verifier := provider.Verifier(&oidc.Config{
+	//		ClientID:       clientID,
+	//		IssuerTemplate: "https://login.microsoftonline.com/{tenantid}/v2.0",
+	//		AllowedTenants: []string{tenantID1, tenantID2},
+	//	})
+	//
+	// This should be preferred over SkipIssuerCheck for multi-tenant providers.
+	IssuerTemplate string
+	// If specified, only tokens from these tenants are accepted. Only used with
+	// IssuerTemplate.
+	AllowedTenants []string
+
 	// Time function to check Token expiry. Defaults to time.Now
 	Now func() time.Time
 
+	// ClockSkew is the leeway allowed when comparing the exp, nbf, and iat claims
+	// against the current time, to account for clock drift between the provider
+	// and this host.
+	//
+	// If zero, no leeway is applied to exp and iat, and nbf is allowed to be up to
+	// 5 minutes in the future.
+	ClockSkew time.Duration
+	// If true, tokens with an iat (issued at) claim in the future are rejected.
+	RejectFutureIssuedAt bool
+	// If non-zero, tokens issued longer than MaxTokenAge ago are rejected. Tokens
+	// without an iat claim are rejected when this is set.
+	MaxTokenAge time.Duration
+
+	// If non-zero, tokens are rejected if the end user authenticated longer than
+	// MaxAge ago, as indicated by the auth_time claim. This should match the
+	// max_age parameter of the authentication request. Tokens without a valid
+	// auth_time claim are rejected when this is set.
+	MaxAge time.Duration
+	// If specified, the acr claim of the token must be one of these values.
+	AcceptedACRValues []string
+	// If specified, the amr claim of the token must contain all of these values.
+	// For example, []string{"mfa"}.
+	RequiredAMRValues []string
+
 	// InsecureSkipSignatureCheck causes this package to skip JWT signature validation.
 	// It's intended for special cases where providers (such as Azure), use the "none"
 	// algorithm.
@@ -123,7 +245,7 @@ type Config struct {
 // verify JWTs. As opposed to Verifier, the context is used for all requests to
 // the upstream JWKs endpoint.
 func (p *Provider) VerifierContext(ctx context.Context, config *Config) *IDTokenVerifier {
-	return p.newVerifier(NewRemoteKeySet(ctx, p.jwksURL), config)
+	return p.newVerifier(p.newRemoteKeySet(ctx), config)
 }
 
 // Verifier returns an IDTokenVerifier that uses the provider's key set to verify JWTs.
@@ -135,6 +257,15 @@ func (p *Provider) Verifier(config *Config) *IDTokenVerifier {
 }
 
 func (p *Provider) newVerifier(keySet KeySet, config *Config) *IDTokenVerifier {
+	p.discoveryMu.RLock()
+	defer p.discoveryMu.RUnlock()
+
+	if p.stopRefreshing != nil {
+		// Algorithms may change, look them up during verification.
+		v := NewVerifier(p.issuer, keySet, config)
+		v.provider = p
+		return v
+	}
 	if len(config.SupportedSigningAlgs) == 0 && len(p.algorithms) > 0 {
 		// Make a copy so we don't modify the config values.
 		cp := &Config{}
@@ -157,6 +288,68 @@ func parseJWT(p string) ([]byte, error) {
 	return payload, nil
 }
 
+// tenantIDPlaceholder is the placeholder for the tenant ID in Config.IssuerTemplate.
+const tenantIDPlaceholder = "{tenantid}"
+
+// matchIssuerTemplate returns the tenant ID if the issuer matches the template.
+func matchIssuerTemplate(template, issuer string) (string, error) {
+	prefix, suffix, ok := strings.Cut(template, tenantIDPlaceholder)
+	if !ok || strings.Contains(suffix, tenantIDPlaceholder) {
+		return "", fmt.Errorf("oidc: invalid configuration, issuer template %q must contain %s exactly once", template, tenantIDPlaceholder)
+	}
+	if !strings.HasPrefix(issuer, prefix) || !strings.HasSuffix(issuer, suffix) || len(issuer) <= len(prefix)+len(suffix) {
+		return "", fmt.Errorf("oidc: id token issuer %q does not match template %q", issuer, template)
+	}
+	tenant := issuer[len(prefix) : len(issuer)-len(suffix)]
+	if strings.ContainsAny(tenant, "/?#") {
+		return "", fmt.Errorf("oidc: id token issuer %q does not match template %q", issuer, template)
+	}
+	return tenant, nil
+}
+
+// JOSEHeader holds the JOSE header parameters of a JWT.
+//
+// See: https://www.rfc-editor.org/rfc/rfc7515#section-4.1
+type JOSEHeader struct {
+	// Algorithm used to sign the token (alg).
+	Algorithm string `json:"alg"`
+	// ID of the key used to sign the token (kid), if set.
+	KeyID string `json:"kid"`
+	// Media type of the token (typ), if set. For example "JWT" or "at+jwt".
+	Type string `json:"typ"`
+	// Content type of the payload (cty), if set.
+	ContentType string `json:"cty"`
+}
+
+// parseJWTHeader decodes the header of a JWT without verifying its signature.
+func parseJWTHeader(p string) (*JOSEHeader, error) {
+	raw, _, ok := strings.Cut(p, ".")
+	if !ok {
+		return nil, errors.New("oidc: malformed jwt, expected 3 parts got 1")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: malformed jwt header: %v", err)
+	}
+	var h JOSEHeader
+	if err := json.Unmarshal(data, &h); err != nil {
+		return nil, fmt.Errorf("oidc: malformed jwt header: %v", err)
+	}
+	return &h, nil
+}
+
+// isMediaType reports whether a typ header value matches a media type. Values are
+// compared case insensitively, and the "application/" prefix may be omitted.
+//
+// See: https://www.rfc-editor.org/rfc/rfc7515#section-4.1.9
+func isMediaType(typ, mediaType string) bool {
+	trim := func(s string) string {
+		s = strings.ToLower(s)
+		return strings.TrimPrefix(s, "application/")
+	}
+	return trim(typ) == trim(mediaType)
+}
+
 func contains(sli []string, ele string) bool {
 	for _, s := range sli {
 		if s == ele {
@@ -168,9 +361,25 @@ func contains(sli []string, ele string) bool {
 
 // Returns the Claims from the distributed JWT token
 func resolveDistributedClaim(ctx context.Context, verifier *IDTokenVerifier, src claimSource) ([]byte, error) {
+	body, err := fetchDistributedClaim(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := verifier.Verify(ctx, body)
+	if err != nil {
+		return nil, fmt.Errorf("malformed response body: %v", err)
+	}
+
+	return token.claims, nil
+}
+
+// fetchDistributedClaim returns the claims JWT served by a distributed claim's
+// endpoint.
+func fetchDistributedClaim(ctx context.Context, src claimSource) (string, error) {
 	req, err := http.NewRequest("GET", src.Endpoint, nil)
 	if err != nil {
-		return nil, fmt.Errorf("malformed request: %v", err)
+		return "", fmt.Errorf("malformed request: %v", err)
 	}
 	if src.AccessToken != "" {
 		req.Header.Set("Authorization", "Bearer "+src.AccessToken)
@@ -178,31 +387,99 @@ func resolveDistributedClaim(ctx context.Context, verifier *IDTokenVerifier, src
 
 	resp, err := doRequest(ctx, req)
 	if err != nil {
-		return nil, fmt.Errorf("oidc: Request to endpoint failed: %v", err)
+		return "", fmt.Errorf("oidc: Request to endpoint failed: %v", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("unable to read response body: %v", err)
+		return "", fmt.Errorf("unable to read response body: %v", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("oidc: request failed: %v", resp.StatusCode)
+		return "", fmt.Errorf("oidc: request failed: %v", resp.StatusCode)
 	}
+	return strings.TrimSpace(string(body)), nil
+}
 
-	token, err := verifier.Verify(ctx, string(body))
+// verifyClaimsJWT verifies a signed JWT holding aggregated or distributed claims
+// with the key set of the Claims Provider named by its iss claim, and returns its
+// payload. Claims JWTs aren't ID Tokens, so no other claims are required, but the
+// exp and nbf claims are checked if present.
+//
+// See: https://openid.net/specs/openid-connect-core-1_0.html#AggregatedDistributedClaims
+func verifyClaimsJWT(ctx context.Context, keySets map[string]KeySet, rawJWT string, now time.Time, clockSkew time.Duration) ([]byte, error) {
+	payload, err := parseJWT(rawJWT)
 	if err != nil {
-		return nil, fmt.Errorf("malformed response body: %v", err)
+		return nil, fmt.Errorf("oidc: malformed jwt: %v", err)
+	}
+	var claims struct {
+		Issuer    string    `json:"iss"`
+		Expiry    *jsonTime `json:"exp"`
+		NotBefore *jsonTime `json:"nbf"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("oidc: failed to unmarshal claims: %v", err)
+	}
+	if claims.Issuer == "" {
+		return nil, errors.New("oidc: claims jwt has no iss claim")
+	}
+	keySet, ok := keySets[claims.Issuer]
+	if !ok || keySet == nil {
+		return nil, fmt.Errorf("oidc: claims jwt issued by untrusted claims provider %q", claims.Issuer)
+	}
+	if claims.Expiry != nil {
+		if exp := time.Time(*claims.Expiry); exp.Before(now.Add(-clockSkew)) {
+			return nil, fmt.Errorf("oidc: claims jwt is expired (expiry: %v)", exp)
+		}
+	}
+	if claims.NotBefore != nil {
+		if nbf := time.Time(*claims.NotBefore); now.Add(clockSkew).Before(nbf) {
+			return nil, fmt.Errorf("oidc: current time %v before the claims jwt nbf (not before) time: %v", now, nbf)
+		}
 	}
+	var algs []string
+	for alg := range supportedAlgorithms {
+		algs = append(algs, alg)
+	}
+	if _, err := verifySignature(ctx, keySet, rawJWT, payload, algs, "claims jwt"); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
 
-	return token.claims, nil
+// VerifyOption is an option that applies additional checks to a single call to
+// VerifyWithOptions.
+type VerifyOption interface {
+	setValue(o *verifyOptions)
+}
+
+type verifyOptions struct {
+	checkNonce bool
+	nonce      string
+}
+
+type setNonce string
+
+func (n setNonce) setValue(o *verifyOptions) {
+	o.checkNonce = true
+	o.nonce = string(n)
+}
+
+// ExpectNonce returns a verify option that requires the ID Token to contain the
+// nonce sent in the authorization request. Tokens with a missing or different
+// nonce are rejected with a *NonceMismatchError.
+//
+//	token, err := verifier.VerifyWithOptions(ctx, rawIDToken, oidc.ExpectNonce(nonce))
+func ExpectNonce(nonce string) VerifyOption {
+	return setNonce(nonce)
 }
 
 // Verify parses a raw ID Token, verifies it's been signed by the provider, performs
 // any additional checks depending on the Config, and returns the payload.
 //
-// Verify does NOT do nonce validation, which is the callers responsibility.
+// Verify does NOT do nonce validation, which is the callers responsibility. Use
+// VerifyWithOptions and ExpectNonce to have the nonce checked.
 //
 // See: https://openid.net/specs/openid-connect-core-1_0.html#IDTokenValidation
 //
@@ -219,6 +496,19 @@ func resolveDistributedClaim(ctx context.Context, verifier *IDTokenVerifier, src
 //
 //	token, err := verifier.Verify(ctx, rawIDToken)
 func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDToken, error) {
+	return v.VerifyWithOptions(ctx, rawIDToken)
+}
+
+// VerifyWithOptions is like Verify, but performs additional checks requested by
+// the options, such as validating the nonce with ExpectNonce.
+//
+//	token, err := verifier.VerifyWithOptions(ctx, rawIDToken, oidc.ExpectNonce(nonce))
+func (v *IDTokenVerifier) VerifyWithOptions(ctx context.Context, rawIDToken string, opts ...VerifyOption) (*IDToken, error) {
+	var o verifyOptions
+	for _, opt := range opts {
+		opt.setValue(&o)
+	}
+
 	// Throw out tokens with invalid claims before trying to verify the token. This lets
 	// us do cheap checks before possibly re-syncing keys.
 	payload, err := parseJWT(rawIDToken)
@@ -244,20 +534,69 @@ func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDTok
 		distributedClaims[cn] = s
 	}
 
+	// These claims are only checked if the config requires it, malformed values
+	// don't fail verification otherwise.
+	var (
+		authTime jsonTime
+		acr      string
+		amr      []string
+	)
+	authTimeErr := unmarshalOptional(token.AuthTime, &authTime)
+	acrErr := unmarshalOptional(token.ACR, &acr)
+	if acrErr != nil {
+		acr = ""
+	}
+	amrErr := unmarshalOptional(token.AMR, &amr)
+	if amrErr != nil {
+		amr = nil
+	}
+
 	t := &IDToken{
 		Issuer:            token.Issuer,
 		Subject:           token.Subject,
 		Audience:          []string(token.Audience),
+		AuthorizedParty:   token.AuthorizedParty,
 		Expiry:            time.Time(token.Expiry),
 		IssuedAt:          time.Time(token.IssuedAt),
 		Nonce:             token.Nonce,
+		AuthTime:          time.Time(authTime),
+		ACR:               acr,
+		AMR:               amr,
 		AccessTokenHash:   token.AtHash,
+		CodeHash:          token.CHash,
+		StateHash:         token.SHash,
 		claims:            payload,
 		distributedClaims: distributedClaims,
 	}
 
+	// The header is covered by the signature, malformed headers are rejected
+	// when verifying it.
+	header, headerErr := parseJWTHeader(rawIDToken)
+	if headerErr == nil {
+		t.Header = *header
+	}
+	if len(v.config.AllowedTypes) > 0 || v.config.StrictTypeCheck {
+		if headerErr != nil {
+			return nil, headerErr
+		}
+		if err := v.checkType(header.Type); err != nil {
+			return nil, err
+		}
+	}
+
 	// Check issuer.
-	if !v.config.SkipIssuerCheck && t.Issuer != v.issuer {
+	if v.config.IssuerTemplate != "" {
+		tenant, err := matchIssuerTemplate(v.config.IssuerTemplate, t.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		if token.TenantID != tenant {
+			return nil, fmt.Errorf("oidc: id token tid claim %q does not match tenant %q of issuer", token.TenantID, tenant)
+		}
+		if len(v.config.AllowedTenants) > 0 && !contains(v.config.AllowedTenants, tenant) {
+			return nil, fmt.Errorf("oidc: id token issued by tenant %q which is not allowed", tenant)
+		}
+	} else if !v.config.SkipIssuerCheck && t.Issuer != v.issuer {
 		// Google sometimes returns "accounts.google.com" as the issuer claim instead of
 		// the required "https://accounts.google.com". Detect this case and allow it only
 		// for Google.
@@ -268,28 +607,38 @@ func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDTok
 		}
 	}
 
-	// If a client ID has been provided, make sure it's part of the audience. SkipClientIDCheck must be true if ClientID is empty.
+	// If a client ID has been provided, make sure it's part of the audience and is the
+	// authorized party if there are multiple audiences. SkipClientIDCheck must be true
+	// if ClientID is empty.
 	//
-	// This check DOES NOT ensure that the ClientID is the party to which the ID Token was issued (i.e. Authorized party).
+	// See: https://openid.net/specs/openid-connect-core-1_0.html#IDTokenValidation
 	if !v.config.SkipClientIDCheck {
 		if v.config.ClientID != "" {
 			if !contains(t.Audience, v.config.ClientID) {
 				return nil, fmt.Errorf("oidc: expected audience %q got %q", v.config.ClientID, t.Audience)
 			}
+			if len(t.Audience) > 1 && t.AuthorizedParty == "" {
+				return nil, fmt.Errorf("oidc: id token has multiple audiences %q but no azp (authorized party) claim", t.Audience)
+			}
+			if t.AuthorizedParty != "" && t.AuthorizedParty != v.config.ClientID {
+				return nil, fmt.Errorf("oidc: expected authorized party %q got %q", v.config.ClientID, t.AuthorizedParty)
+			}
 		} else {
 			return nil, fmt.Errorf("oidc: invalid configuration, clientID must be provided or SkipClientIDCheck must be set")
 		}
 	}
 
+	now := time.Now
+	if v.config.Now != nil {
+		now = v.config.Now
+	}
+	nowTime := now()
+	t.now = now
+	t.clockSkew = v.config.ClockSkew
+
 	// If a SkipExpiryCheck is false, make sure token is not expired.
 	if !v.config.SkipExpiryCheck {
-		now := time.Now
-		if v.config.Now != nil {
-			now = v.config.Now
-		}
-		nowTime := now()
-
-		if t.Expiry.Before(nowTime) {
+		if t.Expiry.Before(nowTime.Add(-v.config.ClockSkew)) {
 			return nil, &TokenExpiredError{Expiry: t.Expiry}
 		}
 
@@ -299,6 +648,9 @@ func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDTok
 			// Set to 5 minutes since this is what other OpenID Connect providers do to deal with clock skew.
 			// https://github.com/AzureAD/azure-activedirectory-identitymodel-extensions-for-dotnet/blob/6.12.2/src/Microsoft.IdentityModel.Tokens/TokenValidationParameters.cs#L149-L153
 			leeway := 5 * time.Minute
+			if v.config.ClockSkew != 0 {
+				leeway = v.config.ClockSkew
+			}
 
 			if nowTime.Add(leeway).Before(nbfTime) {
 				return nil, fmt.Errorf("oidc: current time %v before the nbf (not before) time: %v", nowTime, nbfTime)
@@ -306,12 +658,88 @@ func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDTok
 		}
 	}
 
+	if v.config.RejectFutureIssuedAt && nowTime.Add(v.config.ClockSkew).Before(t.IssuedAt) {
+		return nil, fmt.Errorf("oidc: id token issued in the future, current time %v before the iat (issued at) time: %v", nowTime, t.IssuedAt)
+	}
+	if v.config.MaxTokenAge != 0 {
+		if t.IssuedAt.IsZero() {
+			return nil, errors.New("oidc: id token has no iat (issued at) claim to check its age")
+		}
+		if t.IssuedAt.Add(v.config.MaxTokenAge + v.config.ClockSkew).Before(nowTime) {
+			return nil, fmt.Errorf("oidc: id token issued at %v is older than the maximum age of %v", t.IssuedAt, v.config.MaxTokenAge)
+		}
+	}
+
+	if v.config.MaxAge != 0 {
+		if authTimeErr != nil {
+			return nil, fmt.Errorf("oidc: malformed auth_time claim: %v", authTimeErr)
+		}
+		if t.AuthTime.IsZero() || t.AuthTime.Add(v.config.MaxAge+v.config.ClockSkew).Before(nowTime) {
+			return nil, &MaxAgeError{AuthTime: t.AuthTime, MaxAge: v.config.MaxAge}
+		}
+	}
+	if len(v.config.AcceptedACRValues) > 0 && acrErr != nil {
+		return nil, fmt.Errorf("oidc: malformed acr claim: %v", acrErr)
+	}
+	if len(v.config.AcceptedACRValues) > 0 && !contains(v.config.AcceptedACRValues, t.ACR) {
+		return nil, &ACRError{ACR: t.ACR, Accepted: v.config.AcceptedACRValues}
+	}
+	if len(v.config.RequiredAMRValues) > 0 && amrErr != nil {
+		return nil, fmt.Errorf("oidc: malformed amr claim: %v", amrErr)
+	}
+	var missingAMR []string
+	for _, amr := range v.config.RequiredAMRValues {
+		if !contains(t.AMR, amr) {
+			missingAMR = append(missingAMR, amr)
+		}
+	}
+	if len(missingAMR) > 0 {
+		return nil, &AMRError{AMR: t.AMR, Missing: missingAMR}
+	}
+
+	if o.checkNonce && (t.Nonce == "" || t.Nonce != o.nonce) {
+		return nil, &NonceMismatchError{Expected: o.nonce, Got: t.Nonce}
+	}
+
 	if v.config.InsecureSkipSignatureCheck {
 		return t, nil
 	}
 
+	algs := v.config.SupportedSigningAlgs
+	if len(algs) == 0 && v.provider != nil {
+		algs = v.provider.signingAlgorithms()
+	}
+	sigAlg, err := verifySignature(ctx, v.keySet, rawIDToken, payload, algs, "id token")
+	if err != nil {
+		return nil, err
+	}
+	t.sigAlgorithm = sigAlg
+
+	return t, nil
+}
+
+// checkType validates the typ header of an ID Token against the config.
+func (v *IDTokenVerifier) checkType(typ string) error {
+	if v.config.StrictTypeCheck && typ != "" && !isMediaType(typ, "JWT") {
+		return fmt.Errorf("oidc: jwt with typ %q is not an id token", typ)
+	}
+	if len(v.config.AllowedTypes) == 0 {
+		return nil
+	}
+	for _, allowed := range v.config.AllowedTypes {
+		if (allowed == "" && typ == "") || (allowed != "" && isMediaType(typ, allowed)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("oidc: id token typ %q is not one of %q", typ, v.config.AllowedTypes)
+}
+
+// verifySignature parses a JWT signed with one of the provided algorithms, verifies
+// the signature using the key set, and returns the signing algorithm. The payload
+// is the one parsed before verification, and must match the signed payload.
+func verifySignature(ctx context.Context, keySet KeySet, rawJWT string, payload []byte, algs []string, tokenType string) (string, error) {
 	var supportedSigAlgs []jose.SignatureAlgorithm
-	for _, alg := range v.config.SupportedSigningAlgs {
+	for _, alg := range algs {
 		supportedSigAlgs = append(supportedSigAlgs, jose.SignatureAlgorithm(alg))
 	}
 	if len(supportedSigAlgs) == 0 {
@@ -319,33 +747,31 @@ func (v *IDTokenVerifier) Verify(ctx context.Context, rawIDToken string) (*IDTok
 		// to the one mandatory algorithm "RS256".
 		supportedSigAlgs = []jose.SignatureAlgorithm{jose.RS256}
 	}
-	jws, err := jose.ParseSigned(rawIDToken, supportedSigAlgs)
+	jws, err := jose.ParseSigned(rawJWT, supportedSigAlgs)
 	if err != nil {
-		return nil, fmt.Errorf("oidc: malformed jwt: %v", err)
+		return "", fmt.Errorf("oidc: malformed jwt: %v", err)
 	}
 
 	switch len(jws.Signatures) {
 	case 0:
-		return nil, fmt.Errorf("oidc: id token not signed")
+		return "", fmt.Errorf("oidc: %s not signed", tokenType)
 	case 1:
 	default:
-		return nil, fmt.Errorf("oidc: multiple signatures on id token not supported")
+		return "", fmt.Errorf("oidc: multiple signatures on %s not supported", tokenType)
 	}
 	sig := jws.Signatures[0]
-	t.sigAlgorithm = sig.Header.Algorithm
 
 	ctx = context.WithValue(ctx, parsedJWTKey, jws)
-	gotPayload, err := v.keySet.VerifySignature(ctx, rawIDToken)
+	gotPayload, err := keySet.VerifySignature(ctx, rawJWT)
 	if err != nil {
-		return nil, fmt.Errorf("failed to verify signature: %v", err)
+		return "", fmt.Errorf("failed to verify signature: %v", err)
 	}
 
 	// Ensure that the payload returned by the square actually matches the payload parsed earlier.
 	if !bytes.Equal(gotPayload, payload) {
-		return nil, errors.New("oidc: internal error, payload parsed did not match previous payload")
+		return "", errors.New("oidc: internal error, payload parsed did not match previous payload")
 	}
-
-	return t, nil
+	return sig.Header.Algorithm, nil
 }
 
 // Nonce returns an auth code option which requires the ID Token created by the
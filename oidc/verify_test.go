@@ -157,6 +157,607 @@ func TestVerify(t *testing.T) {
 	}
 }
 
+func TestVerifyClockSkew(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	unix := func(d time.Duration) string {
+		return strconv.FormatInt(now.Add(d).Unix(), 10)
+	}
+	tests := []verificationTest{
+		{
+			name:    "expired within clock skew",
+			idToken: `{"iss":"https://foo","exp":` + unix(-time.Minute) + `}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				ClockSkew:         2 * time.Minute,
+				Now:               func() time.Time { return now },
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "expired beyond clock skew",
+			idToken: `{"iss":"https://foo","exp":` + unix(-3*time.Minute) + `}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				ClockSkew:         2 * time.Minute,
+				Now:               func() time.Time { return now },
+			},
+			signKey:       newRSAKey(t),
+			wantErrExpiry: true,
+		},
+		{
+			name:    "nbf beyond clock skew",
+			idToken: `{"iss":"https://foo","nbf":` + unix(3*time.Minute) + `,"exp":` + unix(time.Hour) + `}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				ClockSkew:         2 * time.Minute,
+				Now:               func() time.Time { return now },
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "iat in future not checked",
+			idToken: `{"iss":"https://foo","iat":` + unix(time.Hour) + `,"exp":` + unix(2*time.Hour) + `}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				Now:               func() time.Time { return now },
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "iat in future",
+			idToken: `{"iss":"https://foo","iat":` + unix(3*time.Minute) + `,"exp":` + unix(time.Hour) + `}`,
+			config: Config{
+				SkipClientIDCheck:    true,
+				ClockSkew:            2 * time.Minute,
+				RejectFutureIssuedAt: true,
+				Now:                  func() time.Time { return now },
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "iat in future within clock skew",
+			idToken: `{"iss":"https://foo","iat":` + unix(time.Minute) + `,"exp":` + unix(time.Hour) + `}`,
+			config: Config{
+				SkipClientIDCheck:    true,
+				ClockSkew:            2 * time.Minute,
+				RejectFutureIssuedAt: true,
+				Now:                  func() time.Time { return now },
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "token within max age",
+			idToken: `{"iss":"https://foo","iat":` + unix(-5*time.Minute) + `,"exp":` + unix(time.Hour) + `}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				MaxTokenAge:       10 * time.Minute,
+				Now:               func() time.Time { return now },
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "token older than max age",
+			idToken: `{"iss":"https://foo","iat":` + unix(-15*time.Minute) + `,"exp":` + unix(time.Hour) + `}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				MaxTokenAge:       10 * time.Minute,
+				Now:               func() time.Time { return now },
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "max age without iat",
+			idToken: `{"iss":"https://foo","exp":` + unix(time.Hour) + `}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				MaxTokenAge:       10 * time.Minute,
+				Now:               func() time.Time { return now },
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestVerifyAuthentication(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	authTime := func(d time.Duration) string {
+		return strconv.FormatInt(now.Add(d).Unix(), 10)
+	}
+	tests := []verificationTest{
+		{
+			name:    "auth_time within max age",
+			idToken: `{"iss":"https://foo","auth_time":` + authTime(-5*time.Minute) + `}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				MaxAge:            10 * time.Minute,
+				Now:               func() time.Time { return now },
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "auth_time older than max age",
+			idToken: `{"iss":"https://foo","auth_time":` + authTime(-15*time.Minute) + `}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				MaxAge:            10 * time.Minute,
+				Now:               func() time.Time { return now },
+			},
+			signKey:   newRSAKey(t),
+			wantErrAs: new(*MaxAgeError),
+		},
+		{
+			name:    "missing auth_time",
+			idToken: `{"iss":"https://foo"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				MaxAge:            10 * time.Minute,
+				Now:               func() time.Time { return now },
+			},
+			signKey:   newRSAKey(t),
+			wantErrAs: new(*MaxAgeError),
+		},
+		{
+			name:    "accepted acr",
+			idToken: `{"iss":"https://foo","acr":"urn:mace:incommon:iap:silver"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				AcceptedACRValues: []string{"urn:mace:incommon:iap:silver", "urn:mace:incommon:iap:gold"},
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "unaccepted acr",
+			idToken: `{"iss":"https://foo","acr":"urn:mace:incommon:iap:bronze"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				AcceptedACRValues: []string{"urn:mace:incommon:iap:silver"},
+			},
+			signKey:   newRSAKey(t),
+			wantErrAs: new(*ACRError),
+		},
+		{
+			name:    "missing acr",
+			idToken: `{"iss":"https://foo"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				AcceptedACRValues: []string{"urn:mace:incommon:iap:silver"},
+			},
+			signKey:   newRSAKey(t),
+			wantErrAs: new(*ACRError),
+		},
+		{
+			name:    "required amr",
+			idToken: `{"iss":"https://foo","amr":["pwd","otp","mfa"]}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				RequiredAMRValues: []string{"mfa", "pwd"},
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "missing required amr",
+			idToken: `{"iss":"https://foo","amr":["pwd"]}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				RequiredAMRValues: []string{"mfa"},
+			},
+			signKey:   newRSAKey(t),
+			wantErrAs: new(*AMRError),
+		},
+		{
+			name:    "malformed claims not checked",
+			idToken: `{"iss":"https://foo","auth_time":"yesterday","acr":1,"amr":"pwd"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "malformed auth_time with max age",
+			idToken: `{"iss":"https://foo","auth_time":"yesterday"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				MaxAge:            10 * time.Minute,
+				Now:               func() time.Time { return now },
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "malformed acr with accepted values",
+			idToken: `{"iss":"https://foo","acr":1}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				AcceptedACRValues: []string{"1"},
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "malformed amr with required values",
+			idToken: `{"iss":"https://foo","amr":"pwd"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				RequiredAMRValues: []string{"pwd"},
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestVerifyAuthenticationClaims(t *testing.T) {
+	test := verificationTest{
+		idToken: `{"iss":"https://foo","auth_time":1685620800,"acr":"1","amr":["pwd","otp"]}`,
+		config: Config{
+			SkipClientIDCheck: true,
+			SkipExpiryCheck:   true,
+		},
+		signKey: newRSAKey(t),
+	}
+	token, err := test.runGetToken(t)
+	if err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	if want := time.Unix(1685620800, 0); !token.AuthTime.Equal(want) {
+		t.Errorf("unexpected auth_time, got=%v, want=%v", token.AuthTime, want)
+	}
+	if token.ACR != "1" {
+		t.Errorf("unexpected acr, got=%q", token.ACR)
+	}
+	if !reflect.DeepEqual(token.AMR, []string{"pwd", "otp"}) {
+		t.Errorf("unexpected amr, got=%q", token.AMR)
+	}
+}
+
+func TestVerifyMalformedAuthenticationClaims(t *testing.T) {
+	test := verificationTest{
+		idToken: `{"iss":"https://foo","auth_time":"yesterday","acr":1,"amr":["pwd",2]}`,
+		config: Config{
+			SkipClientIDCheck: true,
+			SkipExpiryCheck:   true,
+		},
+		signKey: newRSAKey(t),
+	}
+	token, err := test.runGetToken(t)
+	if err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	if !token.AuthTime.IsZero() || token.ACR != "" || token.AMR != nil {
+		t.Errorf("expected malformed claims to be ignored, got auth_time=%v, acr=%q, amr=%q", token.AuthTime, token.ACR, token.AMR)
+	}
+}
+
+func TestVerifyIssuerTemplate(t *testing.T) {
+	const (
+		template = "https://login.example.com/{tenantid}/v2.0"
+		tenant1  = "9188040d-6c67-4c5b-b112-36a304b66dad"
+		tenant2  = "72f988bf-86f1-41af-91ab-2d7cd011db47"
+	)
+	tests := []verificationTest{
+		{
+			name:    "matching tenant",
+			issuer:  template,
+			idToken: `{"iss":"https://login.example.com/` + tenant1 + `/v2.0","tid":"` + tenant1 + `"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				IssuerTemplate:    template,
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "allowed tenant",
+			idToken: `{"iss":"https://login.example.com/` + tenant1 + `/v2.0","tid":"` + tenant1 + `"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				IssuerTemplate:    template,
+				AllowedTenants:    []string{tenant2, tenant1},
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "tenant not allowed",
+			idToken: `{"iss":"https://login.example.com/` + tenant1 + `/v2.0","tid":"` + tenant1 + `"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				IssuerTemplate:    template,
+				AllowedTenants:    []string{tenant2},
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "mismatched tid",
+			idToken: `{"iss":"https://login.example.com/` + tenant1 + `/v2.0","tid":"` + tenant2 + `"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				IssuerTemplate:    template,
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "missing tid",
+			idToken: `{"iss":"https://login.example.com/` + tenant1 + `/v2.0"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				IssuerTemplate:    template,
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "issuer not matching template",
+			idToken: `{"iss":"https://evil.example.com/` + tenant1 + `/v2.0","tid":"` + tenant1 + `"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				IssuerTemplate:    template,
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "tenant with multiple path segments",
+			idToken: `{"iss":"https://login.example.com/a/b/v2.0","tid":"a/b"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				IssuerTemplate:    template,
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "empty tenant",
+			idToken: `{"iss":"https://login.example.com//v2.0","tid":""}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				IssuerTemplate:    template,
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "template without placeholder",
+			idToken: `{"iss":"https://login.example.com/v2.0","tid":""}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				IssuerTemplate:    "https://login.example.com/v2.0",
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestVerifyType(t *testing.T) {
+	tests := []verificationTest{
+		{
+			name:    "no typ",
+			idToken: `{"iss":"https://foo"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				StrictTypeCheck:   true,
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "JWT typ",
+			idToken: `{"iss":"https://foo"}`,
+			typ:     "JWT",
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				StrictTypeCheck:   true,
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "access token not checked",
+			idToken: `{"iss":"https://foo"}`,
+			typ:     "at+jwt",
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "access token strict",
+			idToken: `{"iss":"https://foo"}`,
+			typ:     "at+jwt",
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				StrictTypeCheck:   true,
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "logout token strict",
+			idToken: `{"iss":"https://foo"}`,
+			typ:     "application/logout+jwt",
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				StrictTypeCheck:   true,
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "allowed typ",
+			idToken: `{"iss":"https://foo"}`,
+			typ:     "application/jwt",
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				AllowedTypes:      []string{"JWT"},
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "typ not allowed",
+			idToken: `{"iss":"https://foo"}`,
+			typ:     "id_token+jwt",
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				AllowedTypes:      []string{"JWT"},
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "missing typ not allowed",
+			idToken: `{"iss":"https://foo"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				AllowedTypes:      []string{"JWT"},
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "missing typ allowed",
+			idToken: `{"iss":"https://foo"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+				AllowedTypes:      []string{"JWT", ""},
+			},
+			signKey: newRSAKey(t),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, test.run)
+	}
+}
+
+func TestVerifyHeader(t *testing.T) {
+	key := newRSAKey(t)
+	key.keyID = "key1"
+	test := verificationTest{
+		idToken: `{"iss":"https://foo"}`,
+		typ:     "JWT",
+		config: Config{
+			SkipClientIDCheck: true,
+			SkipExpiryCheck:   true,
+		},
+		signKey: key,
+	}
+	token, err := test.runGetToken(t)
+	if err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+	want := JOSEHeader{Algorithm: RS256, KeyID: "key1", Type: "JWT"}
+	if token.Header != want {
+		t.Errorf("unexpected header, got=%+v, want=%+v", token.Header, want)
+	}
+}
+
+// Verify must keep its original signature, callers may store it as a method value
+// or satisfy their own interfaces with it.
+var _ interface {
+	Verify(context.Context, string) (*IDToken, error)
+} = (*IDTokenVerifier)(nil)
+
+func TestVerifyNonce(t *testing.T) {
+	tests := []verificationTest{
+		{
+			name:    "good nonce",
+			idToken: `{"iss":"https://foo","nonce":"foo"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+			},
+			opts:    []VerifyOption{ExpectNonce("foo")},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "nonce not checked",
+			idToken: `{"iss":"https://foo","nonce":"foo"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "mismatched nonce",
+			idToken: `{"iss":"https://foo","nonce":"foo"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+			},
+			opts:         []VerifyOption{ExpectNonce("bar")},
+			signKey:      newRSAKey(t),
+			wantErrNonce: true,
+		},
+		{
+			name:    "missing nonce",
+			idToken: `{"iss":"https://foo"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+			},
+			opts:         []VerifyOption{ExpectNonce("foo")},
+			signKey:      newRSAKey(t),
+			wantErrNonce: true,
+		},
+		{
+			name:    "empty expected nonce",
+			idToken: `{"iss":"https://foo"}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+			},
+			opts:         []VerifyOption{ExpectNonce("")},
+			signKey:      newRSAKey(t),
+			wantErrNonce: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, test.run)
+	}
+}
+
 func TestVerifyAudience(t *testing.T) {
 	tests := []verificationTest{
 		{
@@ -180,12 +781,60 @@ func TestVerifyAudience(t *testing.T) {
 		},
 		{
 			name:    "multiple audiences, one matches",
+			idToken: `{"iss":"https://foo","aud":["client1","client2"],"azp":"client2"}`,
+			config: Config{
+				ClientID:        "client2",
+				SkipExpiryCheck: true,
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "multiple audiences without authorized party",
 			idToken: `{"iss":"https://foo","aud":["client1","client2"]}`,
 			config: Config{
 				ClientID:        "client2",
 				SkipExpiryCheck: true,
 			},
 			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "multiple audiences, mismatched authorized party",
+			idToken: `{"iss":"https://foo","aud":["client1","client2"],"azp":"client1"}`,
+			config: Config{
+				ClientID:        "client2",
+				SkipExpiryCheck: true,
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "single audience with matching authorized party",
+			idToken: `{"iss":"https://foo","aud":"client1","azp":"client1"}`,
+			config: Config{
+				ClientID:        "client1",
+				SkipExpiryCheck: true,
+			},
+			signKey: newRSAKey(t),
+		},
+		{
+			name:    "single audience with mismatched authorized party",
+			idToken: `{"iss":"https://foo","aud":"client1","azp":"client2"}`,
+			config: Config{
+				ClientID:        "client1",
+				SkipExpiryCheck: true,
+			},
+			signKey: newRSAKey(t),
+			wantErr: true,
+		},
+		{
+			name:    "multiple audiences, skip client ID check",
+			idToken: `{"iss":"https://foo","aud":["client1","client2"]}`,
+			config: Config{
+				SkipClientIDCheck: true,
+				SkipExpiryCheck:   true,
+			},
+			signKey: newRSAKey(t),
 		},
 	}
 	for _, test := range tests {
@@ -495,6 +1144,149 @@ func TestDistClaimResolver(t *testing.T) {
 
 }
 
+func TestResolveClaims(t *testing.T) {
+	opKey := newRSAKey(t)
+	groupsKey := newRSAKey(t)
+	addressKey := newECDSAKey(t)
+
+	// Claims JWTs don't carry aud or exp, which are only required for ID Tokens.
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer 1234" {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		io.WriteString(w, groupsKey.sign(t, []byte(`{"iss":"https://groups","groups":["admins","users"]}`)))
+	}))
+	defer s.Close()
+
+	aggregated := addressKey.sign(t, []byte(`{"iss":"https://address","address":{"country":"US"},"phone_number":"+1 555 0100"}`))
+	idToken := `{
+		"iss":"https://foo","aud":"client1","email":"janedoe@email.com",
+		"_claim_names": {"groups": "src1", "address": "src2", "phone_number": "src2"},
+		"_claim_sources": {
+			"src1": {"endpoint": "` + s.URL + `/groups", "access_token": "1234"},
+			"src2": {"JWT": "` + aggregated + `"}
+		}
+	}`
+	vt := verificationTest{
+		idToken: idToken,
+		config: Config{
+			ClientID:        "client1",
+			SkipExpiryCheck: true,
+		},
+		signKey: opKey,
+	}
+	token, err := vt.runGetToken(t)
+	if err != nil {
+		t.Fatalf("Verify() failed: %v", err)
+	}
+
+	ctx := ClientContext(context.Background(), s.Client())
+	keySet := func(key *signingKey) KeySet {
+		return &StaticKeySet{PublicKeys: []crypto.PublicKey{key.pub}}
+	}
+	tests := []struct {
+		name    string
+		keySets map[string]KeySet
+	}{
+		{
+			name:    "untrusted claims provider",
+			keySets: map[string]KeySet{"https://groups": keySet(groupsKey)},
+		},
+		{
+			name: "claims signed by a different key",
+			keySets: map[string]KeySet{
+				"https://groups":  keySet(groupsKey),
+				"https://address": keySet(opKey),
+			},
+		},
+	}
+	for _, test := range tests {
+		if err := token.ResolveClaims(ctx, test.keySets); err == nil {
+			t.Fatalf("%s: expected error resolving claims", test.name)
+		}
+	}
+
+	err = token.ResolveClaims(ctx, map[string]KeySet{
+		"https://groups":  keySet(groupsKey),
+		"https://address": keySet(addressKey),
+	})
+	if err != nil {
+		t.Fatalf("ResolveClaims() failed: %v", err)
+	}
+
+	var claims map[string]interface{}
+	if err := token.Claims(&claims); err != nil {
+		t.Fatalf("Claims() failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"iss":          "https://foo",
+		"aud":          "client1",
+		"email":        "janedoe@email.com",
+		"groups":       []interface{}{"admins", "users"},
+		"address":      map[string]interface{}{"country": "US"},
+		"phone_number": "+1 555 0100",
+	}
+	if !reflect.DeepEqual(claims, want) {
+		t.Errorf("unexpected claims after resolution, got=%v, want=%v", claims, want)
+	}
+}
+
+func TestResolveClaimsExpiry(t *testing.T) {
+	now := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+	unix := func(d time.Duration) string {
+		return strconv.FormatInt(now.Add(d).Unix(), 10)
+	}
+	claimsKey := newRSAKey(t)
+	keySets := map[string]KeySet{
+		"https://claims": &StaticKeySet{PublicKeys: []crypto.PublicKey{claimsKey.pub}},
+	}
+
+	tests := []struct {
+		name      string
+		times     string
+		clockSkew time.Duration
+		wantErr   bool
+	}{
+		{name: "no exp or nbf"},
+		{name: "valid", times: `,"exp":` + unix(time.Hour) + `,"nbf":` + unix(-time.Hour)},
+		{name: "expired", times: `,"exp":` + unix(-time.Minute), wantErr: true},
+		{name: "expired within clock skew", times: `,"exp":` + unix(-time.Minute), clockSkew: 2 * time.Minute},
+		{name: "not yet valid", times: `,"nbf":` + unix(time.Minute), wantErr: true},
+		{name: "not yet valid within clock skew", times: `,"nbf":` + unix(time.Minute), clockSkew: 2 * time.Minute},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			claimsJWT := claimsKey.sign(t, []byte(`{"iss":"https://claims","groups":["admins"]`+test.times+`}`))
+			vt := verificationTest{
+				idToken: `{"iss":"https://foo","aud":"client1","exp":` + unix(time.Hour) + `,
+					"_claim_names": {"groups": "src1"},
+					"_claim_sources": {"src1": {"JWT": "` + claimsJWT + `"}}}`,
+				config: Config{
+					ClientID:  "client1",
+					ClockSkew: test.clockSkew,
+					Now:       func() time.Time { return now },
+				},
+				signKey: newRSAKey(t),
+			}
+			token, err := vt.runGetToken(t)
+			if err != nil {
+				t.Fatalf("Verify() failed: %v", err)
+			}
+			err = token.ResolveClaims(context.Background(), keySets)
+			if err != nil {
+				if !test.wantErr {
+					t.Errorf("ResolveClaims() failed: %v", err)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Errorf("expected error resolving claims")
+			}
+		})
+	}
+}
+
 type resolverTest struct {
 	// Name of the subtest.
 	name string
@@ -569,16 +1361,25 @@ type verificationTest struct {
 	// If not provided defaults to signKey. Only useful when
 	// testing invalid signatures.
 	verificationKey *signingKey
+	// Optional typ header of the signed ID Token.
+	typ string
+
+	config Config
+	// Options passed to Verify.
+	opts []VerifyOption
 
-	config        Config
 	wantErr       bool
 	wantErrExpiry bool
+	wantErrNonce  bool
+	// If set, a pointer to an error type the returned error must match using
+	// errors.As, e.g. new(*MaxAgeError).
+	wantErrAs interface{}
 }
 
 func (v verificationTest) runGetToken(t *testing.T) (*IDToken, error) {
 	var token string
 	if v.signKey != nil {
-		token = v.signKey.sign(t, []byte(v.idToken))
+		token = v.signKey.signWithType(t, []byte(v.idToken), v.typ)
 	} else {
 		token = base64.RawURLEncoding.EncodeToString([]byte(`{alg: "none"}`))
 		token += "."
@@ -600,15 +1401,15 @@ func (v verificationTest) runGetToken(t *testing.T) (*IDToken, error) {
 	}
 	verifier := NewVerifier(issuer, ks, &v.config)
 
-	return verifier.Verify(ctx, token)
+	return verifier.VerifyWithOptions(ctx, token, v.opts...)
 }
 
 func (v verificationTest) run(t *testing.T) {
 	_, err := v.runGetToken(t)
-	if err != nil && !v.wantErr && !v.wantErrExpiry {
+	if err != nil && !v.wantErr && !v.wantErrExpiry && !v.wantErrNonce && v.wantErrAs == nil {
 		t.Errorf("%v", err)
 	}
-	if err == nil && (v.wantErr || v.wantErrExpiry) {
+	if err == nil && (v.wantErr || v.wantErrExpiry || v.wantErrNonce || v.wantErrAs != nil) {
 		t.Errorf("expected error")
 	}
 	if v.wantErrExpiry {
@@ -617,4 +1418,13 @@ func (v verificationTest) run(t *testing.T) {
 			t.Errorf("expected *TokenExpiryError but got %q", err)
 		}
 	}
+	if v.wantErrNonce {
+		var errNonce *NonceMismatchError
+		if !errors.As(err, &errNonce) {
+			t.Errorf("expected *NonceMismatchError but got %q", err)
+		}
+	}
+	if v.wantErrAs != nil && err != nil && !errors.As(err, v.wantErrAs) {
+		t.Errorf("expected %T but got %q", v.wantErrAs, err)
+	}
 }
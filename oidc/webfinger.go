@@ -0,0 +1,128 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// issuerRel is the WebFinger link relation used to locate an OpenID Provider.
+const issuerRel = "http://openid.net/specs/connect/1.0/issuer"
+
+// DiscoverIssuer uses WebFinger to find the issuer URL of the OpenID Provider
+// for a user identifier, such as an email address entered on a login page. The
+// returned issuer can be passed to NewProvider.
+//
+// Identifiers are normalized as described by OpenID Connect Discovery. E-mail
+// style identifiers like "joe@example.com" are treated as "acct:" URIs, and
+// other identifiers like "example.com/joe" as https URLs. The WebFinger query
+// is sent to the host of the normalized identifier.
+//
+//	issuer, err := oidc.DiscoverIssuer(ctx, "joe@example.com")
+//	if err != nil {
+//		// handle error
+//	}
+//	provider, err := oidc.NewProvider(ctx, issuer)
+//
+// See: https://openid.net/specs/openid-connect-discovery-1_0.html#IssuerDiscovery
+func DiscoverIssuer(ctx context.Context, identifier string) (string, error) {
+	resource, host, err := normalizeIdentifier(identifier)
+	if err != nil {
+		return "", err
+	}
+
+	u := url.URL{
+		Scheme: "https",
+		Host:   host,
+		Path:   "/.well-known/webfinger",
+		RawQuery: url.Values{
+			"resource": {resource},
+			"rel":      {issuerRel},
+		}.Encode(),
+	}
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("oidc: create GET request: %v", err)
+	}
+	req.Header.Set("Accept", "application/jrd+json")
+
+	resp, err := doRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("unable to read response body: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", resp.Status, body)
+	}
+
+	var jrd struct {
+		Links []struct {
+			Rel  string `json:"rel"`
+			Href string `json:"href"`
+		} `json:"links"`
+	}
+	if err := json.Unmarshal(body, &jrd); err != nil {
+		return "", fmt.Errorf("oidc: failed to decode webfinger response: %v", err)
+	}
+	for _, link := range jrd.Links {
+		if link.Rel != issuerRel {
+			continue
+		}
+		issuer, err := url.Parse(link.Href)
+		if err != nil || issuer.Scheme != "https" || issuer.Host == "" || issuer.RawQuery != "" || issuer.Fragment != "" {
+			return "", fmt.Errorf("oidc: webfinger returned invalid issuer %q", link.Href)
+		}
+		return link.Href, nil
+	}
+	return "", fmt.Errorf("oidc: webfinger response for %q has no issuer link", resource)
+}
+
+// normalizeIdentifier converts user input into the resource of a WebFinger
+// query, and returns the host the query should be sent to.
+//
+// See: https://openid.net/specs/openid-connect-discovery-1_0.html#NormalizationSteps
+func normalizeIdentifier(identifier string) (resource, host string, err error) {
+	identifier = strings.TrimSpace(identifier)
+	if identifier == "" {
+		return "", "", errors.New("oidc: empty identifier")
+	}
+
+	if strings.HasPrefix(identifier, "acct:") {
+		i := strings.LastIndex(identifier, "@")
+		if i < 0 || i == len(identifier)-1 {
+			return "", "", fmt.Errorf("oidc: invalid acct identifier %q", identifier)
+		}
+		return identifier, identifier[i+1:], nil
+	}
+
+	// Identifiers without a scheme are e-mail addresses if they only have a
+	// user and host, and URLs with an https scheme otherwise.
+	if !strings.HasPrefix(identifier, "https://") && !strings.HasPrefix(identifier, "http://") {
+		at := strings.LastIndex(identifier, "@")
+		if at > 0 && !strings.ContainsAny(identifier[at+1:], ":/?#") {
+			return "acct:" + identifier, identifier[at+1:], nil
+		}
+		identifier = "https://" + identifier
+	}
+
+	u, err := url.Parse(identifier)
+	if err != nil {
+		return "", "", fmt.Errorf("oidc: invalid identifier %q: %v", identifier, err)
+	}
+	if u.Host == "" {
+		return "", "", fmt.Errorf("oidc: invalid identifier %q: no host", identifier)
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String(), u.Host, nil
+}
@@ -0,0 +1,117 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeIdentifier(t *testing.T) {
+	// Test cases from the OpenID Connect Discovery spec examples.
+	tests := []struct {
+		identifier   string
+		wantResource string
+		wantHost     string
+		wantErr      bool
+	}{
+		{"joe@example.com", "acct:joe@example.com", "example.com", false},
+		{"  joe@example.com ", "acct:joe@example.com", "example.com", false},
+		{"example.com", "https://example.com", "example.com", false},
+		{"example.com:8080", "https://example.com:8080", "example.com:8080", false},
+		{"https://example.com/joe", "https://example.com/joe", "example.com", false},
+		{"example.com/joe#fragment", "https://example.com/joe", "example.com", false},
+		{"joe@example.com:8080", "https://joe@example.com:8080", "example.com:8080", false},
+		{"joe@localhost/path", "https://joe@localhost/path", "localhost", false},
+		{"acct:juliet%40capulet.example@shopping.example.com", "acct:juliet%40capulet.example@shopping.example.com", "shopping.example.com", false},
+		{"acct:joe", "", "", true},
+		{"", "", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.identifier, func(t *testing.T) {
+			resource, host, err := normalizeIdentifier(test.identifier)
+			if err != nil {
+				if !test.wantErr {
+					t.Errorf("normalizeIdentifier() failed: %v", err)
+				}
+				return
+			}
+			if test.wantErr {
+				t.Fatalf("normalizeIdentifier(): expected error")
+			}
+			if resource != test.wantResource {
+				t.Errorf("normalizeIdentifier() unexpected resource, got=%s, want=%s", resource, test.wantResource)
+			}
+			if host != test.wantHost {
+				t.Errorf("normalizeIdentifier() unexpected host, got=%s, want=%s", host, test.wantHost)
+			}
+		})
+	}
+}
+
+func TestDiscoverIssuer(t *testing.T) {
+	var gotResource string
+	s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/webfinger" || r.URL.Query().Get("rel") != issuerRel {
+			http.NotFound(w, r)
+			return
+		}
+		gotResource = r.URL.Query().Get("resource")
+		w.Header().Set("Content-Type", "application/jrd+json")
+		fmt.Fprintf(w, `{
+			"subject": %q,
+			"links": [
+				{"rel": "http://webfinger.net/rel/profile-page", "href": "https://example.com/profile"},
+				{"rel": %q, "href": "https://login.example.com"}
+			]
+		}`, gotResource, issuerRel)
+	}))
+	defer s.Close()
+
+	host := strings.TrimPrefix(s.URL, "https://")
+	ctx := ClientContext(context.Background(), s.Client())
+
+	issuer, err := DiscoverIssuer(ctx, "acct:joe@"+host)
+	if err != nil {
+		t.Fatalf("DiscoverIssuer() failed: %v", err)
+	}
+	if issuer != "https://login.example.com" {
+		t.Errorf("DiscoverIssuer() unexpected issuer, got=%s", issuer)
+	}
+	if gotResource != "acct:joe@"+host {
+		t.Errorf("DiscoverIssuer() unexpected resource, got=%s", gotResource)
+	}
+
+	if _, err := DiscoverIssuer(ctx, host+"/joe"); err != nil {
+		t.Fatalf("DiscoverIssuer() failed: %v", err)
+	}
+	if gotResource != s.URL+"/joe" {
+		t.Errorf("DiscoverIssuer() unexpected resource, got=%s", gotResource)
+	}
+}
+
+func TestDiscoverIssuerInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"no issuer link", `{"links":[]}`},
+		{"http issuer", `{"links":[{"rel":"` + issuerRel + `","href":"http://login.example.com"}]}`},
+		{"malformed", `{"links":`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, test.body)
+			}))
+			defer s.Close()
+
+			ctx := ClientContext(context.Background(), s.Client())
+			if _, err := DiscoverIssuer(ctx, s.URL); err == nil {
+				t.Errorf("DiscoverIssuer(): expected error")
+			}
+		})
+	}
+}